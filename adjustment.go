@@ -0,0 +1,386 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+)
+
+// Causal effect identification by covariate adjustment.
+// The criteria are checked on the DAG of bn.edges; the effects are
+// estimated either from the CPTs of the network or, when a dataset
+// is supplied, from the frequencies of its rows. The latter only
+// needs columns for the treatment, the outcome and the adjustment
+// set, so it also works when other nodes are latent.
+
+// Reports whether z satisfies the backdoor criterion relative to
+// (treatment, outcome): no node in z is a descendant of the
+// treatment, and z blocks every path between treatment and outcome
+// that starts with an arrow into the treatment
+// - panics if one of the names does not exist in the network
+func (bn *BayesianNetwork) IsBackdoorSet(treatment, outcome string, z []string) bool {
+	bn.mustGetNodes(append([]string{treatment, outcome}, z...))
+
+	g := bn.dag()
+	descendants := g.closure(treatment, g.children)
+	for _, name := range z {
+		if name == treatment || name == outcome || descendants[name] {
+			return false
+		}
+	}
+
+	// only the backdoor paths remain once the edges out of
+	// the treatment are removed
+	backdoor := g.without(func(parent, child string) bool {
+		return parent == treatment
+	})
+	return backdoor.dSeparated([]string{treatment}, []string{outcome}, z)
+}
+
+// Enumerates every set satisfying the backdoor criterion, smallest
+// sets first. The sets are subsets of the non-descendants of the
+// treatment, so this is exponential in their number
+func (bn *BayesianNetwork) BackdoorSets(treatment, outcome string) [][]string {
+	bn.mustGetNodes([]string{treatment, outcome})
+
+	g := bn.dag()
+	descendants := g.closure(treatment, g.children)
+
+	candidates := make(BayNodes, 0, len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		name := node.Name()
+		if name != treatment && name != outcome && !descendants[name] {
+			candidates = append(candidates, node)
+		}
+	}
+
+	// the subsets are tested one at a time, in colex order of
+	// each size, rather than built up front
+	var sets [][]string
+	n := len(candidates)
+	for k := 0; k <= n; k++ {
+		c := make([]int, k)
+		for i := range c {
+			c[i] = i
+		}
+		for {
+			z := make([]string, k)
+			for i, j := range c {
+				z[i] = candidates[j].Name()
+			}
+			if bn.IsBackdoorSet(treatment, outcome, z) {
+				sets = append(sets, z)
+			}
+			if !nextCombination(c, n) {
+				break
+			}
+		}
+	}
+	return sets
+}
+
+// advances c to the next k-combination of 0..n-1 in colex order
+// - reports false once c was the last one
+func nextCombination(c []int, n int) bool {
+	k := len(c)
+	for j := 0; j < k; j++ {
+		if j == k-1 || c[j]+1 < c[j+1] {
+			c[j]++
+			for i := 0; i < j; i++ {
+				c[i] = i
+			}
+			return c[k-1] < n
+		}
+	}
+	return false
+}
+
+// Enumerates the backdoor sets of which no proper subset
+// is a backdoor set itself
+func (bn *BayesianNetwork) MinimalBackdoorSets(treatment, outcome string) [][]string {
+	var minimal [][]string
+	for _, z := range bn.BackdoorSets(treatment, outcome) {
+		redundant := false
+		for _, m := range minimal {
+			if isSubset(m, z) {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			minimal = append(minimal, z)
+		}
+	}
+	return minimal
+}
+
+// Reports whether z satisfies the frontdoor criterion relative to
+// (treatment, outcome):
+// 1. z intercepts every directed path from the treatment to the outcome
+// 2. there is no unblocked backdoor path from the treatment to z
+// 3. every backdoor path from z to the outcome is blocked by the treatment
+// - panics if one of the names does not exist in the network
+func (bn *BayesianNetwork) IsFrontdoorSet(treatment, outcome string, z []string) bool {
+	bn.mustGetNodes(append([]string{treatment, outcome}, z...))
+
+	inZ := make(map[string]bool, len(z))
+	for _, name := range z {
+		if name == treatment || name == outcome {
+			return false
+		}
+		inZ[name] = true
+	}
+
+	g := bn.dag()
+
+	// 1. no directed path avoiding z
+	intercepted := g.without(func(parent, child string) bool {
+		return inZ[parent] || inZ[child]
+	})
+	if intercepted.closure(treatment, intercepted.children)[outcome] {
+		return false
+	}
+
+	// 2. treatment ⊥ z once the edges out of the treatment are removed
+	outOfTreatment := g.without(func(parent, child string) bool {
+		return parent == treatment
+	})
+	if !outOfTreatment.dSeparated([]string{treatment}, z, nil) {
+		return false
+	}
+
+	// 3. z ⊥ outcome | treatment once the edges out of z are removed
+	outOfZ := g.without(func(parent, child string) bool {
+		return inZ[parent]
+	})
+	return outOfZ.dSeparated(z, []string{outcome}, []string{treatment})
+}
+
+// The average causal effect P(outcome=T | do(treatment=T)) -
+// P(outcome=T | do(treatment=F)) computed on the mutilated networks
+func (bn *BayesianNetwork) AverageCausalEffect(treatment, outcome string) (float64, error) {
+	effect := 0.0
+	for _, value := range []string{"T", "F"} {
+		stats, err := bn.InterventionalQuery(map[string]string{treatment: value}, nil,
+			(*BayesianNetwork).ExactInference)
+		if err != nil {
+			return 0, err
+		}
+		if value == "T" {
+			effect += stats[outcome][0]
+		} else {
+			effect -= stats[outcome][0]
+		}
+	}
+	return effect, nil
+}
+
+// Estimates the average causal effect by the backdoor adjustment
+//
+//	P(o | do(t)) = sum_z P(o | t, z) P(z)
+//
+// from the CPTs of the network, or from the dataset if it is not nil.
+// z must satisfy the backdoor criterion
+func (bn *BayesianNetwork) BackdoorEffect(treatment, outcome string, z []string, ds *Dataset) (float64, error) {
+	if !bn.IsBackdoorSet(treatment, outcome, z) {
+		return 0, fmt.Errorf("%v is not a backdoor set for %s -> %s", z, treatment, outcome)
+	}
+	prob, err := bn.probabilitySource(ds, append([]string{treatment, outcome}, z...))
+	if err != nil {
+		return 0, err
+	}
+
+	effect := 0.0
+	for _, zs := range assignments(z) {
+		pz, err := prob(zs, nil)
+		if err != nil {
+			return 0, err
+		}
+		if pz == 0 {
+			continue
+		}
+		for _, t := range []string{"T", "F"} {
+			given := with(zs, treatment, t)
+			po, err := prob(map[string]string{outcome: "T"}, given)
+			if err != nil {
+				return 0, err
+			}
+			effect += sign(t) * po * pz
+		}
+	}
+	return effect, nil
+}
+
+// Estimates the average causal effect by the frontdoor adjustment
+//
+//	P(o | do(t)) = sum_z P(z | t) sum_t' P(o | t', z) P(t')
+//
+// from the CPTs of the network, or from the dataset if it is not nil.
+// z must satisfy the frontdoor criterion
+func (bn *BayesianNetwork) FrontdoorEffect(treatment, outcome string, z []string, ds *Dataset) (float64, error) {
+	if !bn.IsFrontdoorSet(treatment, outcome, z) {
+		return 0, fmt.Errorf("%v is not a frontdoor set for %s -> %s", z, treatment, outcome)
+	}
+	prob, err := bn.probabilitySource(ds, append([]string{treatment, outcome}, z...))
+	if err != nil {
+		return 0, err
+	}
+
+	// sum_t' P(o | t', z) P(t') does not depend on t
+	inner := func(zs map[string]string) (float64, error) {
+		sum := 0.0
+		for _, t := range []string{"T", "F"} {
+			pt, err := prob(map[string]string{treatment: t}, nil)
+			if err != nil {
+				return 0, err
+			}
+			if pt == 0 {
+				continue
+			}
+			po, err := prob(map[string]string{outcome: "T"}, with(zs, treatment, t))
+			if err != nil {
+				return 0, err
+			}
+			sum += po * pt
+		}
+		return sum, nil
+	}
+
+	effect := 0.0
+	for _, zs := range assignments(z) {
+		s, err := inner(zs)
+		if err != nil {
+			return 0, err
+		}
+		for _, t := range []string{"T", "F"} {
+			pz, err := prob(zs, map[string]string{treatment: t})
+			if err != nil {
+				return 0, err
+			}
+			effect += sign(t) * pz * s
+		}
+	}
+	return effect, nil
+}
+
+// P(event | given)
+type probability func(event, given map[string]string) (float64, error)
+
+// the probabilities of the network, or the frequencies of the
+// dataset if it is not nil. The dataset needs the given columns
+func (bn *BayesianNetwork) probabilitySource(ds *Dataset, columns []string) (probability, error) {
+	if ds == nil {
+		return bn.conditionalProbability, nil
+	}
+
+	for _, name := range columns {
+		if _, ok := ds.index[name]; !ok {
+			return nil, fmt.Errorf("dataset has no column '%s'", name)
+		}
+	}
+	return ds.conditionalFrequency, nil
+}
+
+// P(event | given) by exact inference
+func (bn *BayesianNetwork) conditionalProbability(event, given map[string]string) (float64, error) {
+	joint, err := bn.LogEvidenceProbability(merge(event, given))
+	if err != nil {
+		return 0, err
+	}
+	marginal, err := bn.LogEvidenceProbability(given)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsInf(marginal, -1) {
+		return 0, fmt.Errorf("P(%v) = 0", given)
+	}
+	return math.Exp(joint - marginal), nil
+}
+
+// relative frequency of the event among the rows matching given
+// - rows with a missing value in one of the columns are skipped
+func (ds *Dataset) conditionalFrequency(event, given map[string]string) (float64, error) {
+	all := merge(event, given)
+	matching, hits := 0, 0
+	for i := 0; i < ds.Len(); i++ {
+		if !ds.matches(i, given) || !ds.observed(i, all) {
+			continue
+		}
+		matching++
+		if ds.matches(i, event) {
+			hits++
+		}
+	}
+	if matching == 0 {
+		return 0, fmt.Errorf("no complete rows with %v", given)
+	}
+	return float64(hits) / float64(matching), nil
+}
+
+// reports whether row i has the given values
+func (ds *Dataset) matches(i int, values map[string]string) bool {
+	for name, value := range values {
+		if ds.Value(i, name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// reports whether row i has a value for every name
+func (ds *Dataset) observed(i int, values map[string]string) bool {
+	for name := range values {
+		if ds.Value(i, name) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// every assignment to the names, in cptKeys order
+func assignments(names []string) []map[string]string {
+	keys := cptKeys(len(names))
+	result := make([]map[string]string, len(keys))
+	for i, key := range keys {
+		result[i] = make(map[string]string, len(names))
+		for j, name := range names {
+			result[i][name] = string(key[j])
+		}
+	}
+	return result
+}
+
+func merge(a, b map[string]string) map[string]string {
+	m := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+func with(m map[string]string, name, value string) map[string]string {
+	return merge(m, map[string]string{name: value})
+}
+
+// +1 for the treated, -1 for the untreated arm of the effect
+func sign(t string) float64 {
+	if t == "T" {
+		return 1
+	}
+	return -1
+}
+
+func isSubset(a, b []string) bool {
+	set := make(map[string]bool, len(b))
+	for _, name := range b {
+		set[name] = true
+	}
+	for _, name := range a {
+		if !set[name] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestBackdoorSets(t *testing.T) {
+	bn := BuildStudentNetwork()
+
+	// P <- I -> R -> U and P <- D -> R -> U are the backdoor paths
+	minimal := fmt.Sprint(bn.MinimalBackdoorSets("P", "U"))
+	if minimal != "[[R] [I D]]" {
+		t.Errorf("MinimalBackdoorSets(P, U): %s", minimal)
+	}
+	if bn.IsBackdoorSet("P", "U", []string{"I"}) || bn.IsBackdoorSet("P", "U", []string{"J"}) {
+		t.Error("{I} leaves P <- D -> R -> U open and J is a descendant of P")
+	}
+	if !bn.IsBackdoorSet("P", "U", []string{"E", "R"}) {
+		t.Error("{E, R} should be a backdoor set")
+	}
+
+	exp, err := bn.AverageCausalEffect("P", "U")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, z := range bn.BackdoorSets("P", "U") {
+		act, err := bn.BackdoorEffect("P", "U", z, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(act-exp) > 1e-9 {
+			t.Errorf("BackdoorEffect(%v): Exp %.4f != %.4f Act", z, exp, act)
+		}
+	}
+
+	Seed(3)
+	act, err := bn.BackdoorEffect("P", "U", []string{"R"}, bn.SampleDataset(20000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(act-exp) > epsilon {
+		t.Errorf("BackdoorEffect from data: Exp %.4f != %.4f Act", exp, act)
+	}
+}
+
+// the classic frontdoor graph: a latent confounder H of X and Y,
+// with the whole effect of X going through M
+func TestFrontdoor(t *testing.T) {
+	h := NewRootNode("H", 0.4)
+	x := NewNode("X", []string{"H"}, map[string]float64{"T": 0.8, "F": 0.2})
+	m := NewNode("M", []string{"X"}, map[string]float64{"T": 0.9, "F": 0.1})
+	y := NewNode("Y", []string{"M", "H"}, map[string]float64{"TT": 0.9, "TF": 0.6, "FT": 0.5, "FF": 0.1})
+	bn := NewBayesianNetwork(h, x, m, y)
+
+	if !bn.IsFrontdoorSet("X", "Y", []string{"M"}) {
+		t.Error("{M} should be a frontdoor set")
+	}
+	if bn.IsFrontdoorSet("X", "Y", []string{"H"}) || bn.IsBackdoorSet("X", "Y", []string{"M"}) {
+		t.Error("{H} is no frontdoor set and {M} no backdoor set")
+	}
+
+	exp, err := bn.AverageCausalEffect("X", "Y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	act, err := bn.FrontdoorEffect("X", "Y", []string{"M"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(act-exp) > 1e-9 {
+		t.Errorf("FrontdoorEffect: Exp %.4f != %.4f Act", exp, act)
+	}
+
+	// H is not observed in the data
+	Seed(5)
+	full := bn.SampleDataset(20000)
+	ds := NewDataset("X", "M", "Y")
+	for i := 0; i < full.Len(); i++ {
+		row := full.Row(i)
+		delete(row, "H")
+		ds.Append(row)
+	}
+
+	act, err = bn.FrontdoorEffect("X", "Y", []string{"M"}, ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(act-exp) > epsilon {
+		t.Errorf("FrontdoorEffect from data: Exp %.4f != %.4f Act", exp, act)
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"bytes"
 	"fmt"
 	// "math"
-	"math/rand"
 	// "sort"
 	// "time"
 )
 
 type BayesianNetwork struct {
+	// name of the network, as used by the
+	// exchange formats (XMLBIF, ...)
+	name string
+	// free-form metadata on the network
+	properties map[string]string
 	// nodesName -> node-pointer map
 	nodes map[string]*Node
 	// connections between nodes
@@ -23,6 +27,17 @@ type BayesianNetwork struct {
 
 // Creates a directed bayesian network from each node
 func NewBayesianNetwork(nodes ...*Node) *BayesianNetwork {
+	bn, err := buildBayesianNetwork(nodes...)
+	if err != nil {
+		panic(err)
+	}
+	return bn
+}
+
+// same as NewBayesianNetwork, but reports invalid
+// networks as an error instead of panicking
+// - used by the decoders, where the input is not trusted
+func buildBayesianNetwork(nodes ...*Node) (*BayesianNetwork, error) {
 	bn := &BayesianNetwork{
 		nodes:     make(map[string]*Node, len(nodes)),
 		nodeIndex: make([]*Node, 0, len(nodes)),
@@ -32,25 +47,29 @@ func NewBayesianNetwork(nodes ...*Node) *BayesianNetwork {
 	// add nodes to network
 	for _, node := range nodes {
 		if err := bn.addNode(node); err != nil {
-			panic(err)
+			return nil, err
 		}
 	}
 
 	// generate connections
 	for _, node := range nodes {
-		bn.addConnections(node.GetParentNames(), node.Name())
+		if err := bn.addConnections(node.GetParentNames(), node.Name()); err != nil {
+			return nil, err
+		}
 	}
 
 	// validate that CPT has the correct dimensions
 	// wrt. number of parents
 	if err := bn.validateCPTs(); err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	// index nodes in a breath first fashion
-	bn.indexNetwork()
+	if err := bn.indexNetwork(nodes); err != nil {
+		return nil, err
+	}
 
-	return bn
+	return bn, nil
 }
 
 // takes the node argument of interest (X5) and the truth-value
@@ -59,6 +78,11 @@ func NewBayesianNetwork(nodes ...*Node) *BayesianNetwork {
 
 // if X5 sample == false:
 
+// Estimates the posterior marginals given the observations by Gibbs
+// sampling: n sweeps of burn-in, then m sweeps that are counted
+// - binary nodes are observed as "T" or "F", continuous nodes
+//   as numbers, e.g. "1.5"
+// - panics if an observed node does not exist or its value is invalid
 func (bn *BayesianNetwork) GibbsSampling(observations map[string]string, n, m int) StatMap {
 
 	// only sample from the variables that
@@ -90,23 +114,29 @@ func (bn *BayesianNetwork) GibbsSampling(observations map[string]string, n, m in
 		}
 	}
 
+	// continuous nodes start at their mean given their parents
+	for _, node := range nodes_of_interest {
+		if node.IsContinuous() {
+			mean, _ := node.moments()
+			node.SetValue(mean)
+		}
+	}
+
+	// deterministic nodes are sampled jointly with
+	// the nodes they depend on
+	blocks, singles := bn.gibbsBlocks(nodes_of_interest)
+
 	// initialize stat gathering
 	ns := NewNetworkStat(bn)
 
 	// run n times before we start registering statistics
 	for i := 0; i < n; i++ {
-		for _, xi := range nodes_of_interest {
-			sample := bn.MarkovBlanketSample(xi)
-			xi.SetAssignment(sample)
-		}
+		bn.gibbsSweep(blocks, singles)
 	}
 
 	// run m times while gathering stats
 	for i := 0; i < m; i++ {
-		for _, xi := range nodes_of_interest {
-			sample := bn.MarkovBlanketSample(xi)
-			xi.SetAssignment(sample)
-		}
+		bn.gibbsSweep(blocks, singles)
 		// update stats
 		ns.Update()
 	}
@@ -117,6 +147,24 @@ func (bn *BayesianNetwork) GibbsSampling(observations map[string]string, n, m in
 	return ns.GetStats()
 }
 
+// resamples every unobserved node once
+func (bn *BayesianNetwork) gibbsSweep(blocks []*gibbsBlock, singles BayNodes) {
+	for _, xi := range singles {
+		switch {
+		case xi.IsContinuous():
+			bn.sampleContinuous(xi)
+		case xi.hasContinuousChild():
+			bn.sampleHybrid(xi)
+		default:
+			sample := bn.MarkovBlanketSample(xi)
+			xi.SetAssignment(sample)
+		}
+	}
+	for _, b := range blocks {
+		bn.sampleBlock(b)
+	}
+}
+
 func (bn *BayesianNetwork) MarkovBlanketSample(node *Node) string {
 	// ******* numerator *******
 	numerator := node.P()
@@ -143,7 +191,7 @@ func (bn *BayesianNetwork) MarkovBlanketSample(node *Node) string {
 	}
 	markovProb := numerator / Z
 
-	random := rand.Float64()
+	random := rng.Float64()
 	if random > markovProb {
 		return "F"
 	}
@@ -154,14 +202,21 @@ func (bn *BayesianNetwork) MarkovBlanketSample(node *Node) string {
 // Given a truth-assignment for a markov blanket,
 // this method updates the nodes to reflect those values.
 // mapping example: map[string]string{ "X1":"F", X3:"T"}
-// - reports an error if just one of the nodes does not exist
+// - reports an error if just one of the nodes does not exist, or
+//   if a value is neither T/F nor a number of a continuous node;
+//   the network is not changed then
 func (bn *BayesianNetwork) UpdateGraphValues(mapping map[string]string) error {
 	for nodeName, value := range mapping {
 		node := bn.nodes[nodeName]
 		if node == nil {
 			return fmt.Errorf("Node '%s' does not exist in network\n\tmapping: %v\n\tnetwork: %v\n", nodeName, mapping, bn.nodeIndex)
 		}
-		node.SetAssignment(value)
+		if err := node.checkAssignment(value); err != nil {
+			return fmt.Errorf("%s: %v", nodeName, err)
+		}
+	}
+	for nodeName, value := range mapping {
+		bn.nodes[nodeName].SetAssignment(value)
 	}
 	return nil
 }
@@ -173,7 +228,7 @@ func (bn *BayesianNetwork) AncestralSampling(n int) StatMap {
 	for i := 0; i < n; i++ {
 		for _, node := range bn.nodeIndex {
 			// fmt.Printf("%s = %s\n", node.Name(), node.AssignmentValue())
-			node.SetAssignment(node.Sample())
+			node.draw()
 		}
 		// upate stats
 		stat.Update()
@@ -237,6 +292,9 @@ func (bn *BayesianNetwork) validateCPTs() error {
 		if err := node.ValidateCPT(); err != nil {
 			return err
 		}
+		if err := node.validateParentKinds(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -244,35 +302,64 @@ func (bn *BayesianNetwork) validateCPTs() error {
 
 // index the graph in a breath-first fashion
 // - guarantees that every parent has an index
-//   that is larger than every one of their children
-func (bn *BayesianNetwork) indexNetwork() {
+//   that is smaller than every one of their children
+// - nodes are indexed by their depth, the length of the
+//   longest path from a root, ties keep the order of nodes
+// - reports an error if the graph has a cycle
+func (bn *BayesianNetwork) indexNetwork(nodes BayNodes) error {
+	depth := make(map[*Node]int, len(nodes))
+	pending := make(map[*Node]int, len(nodes))
 
 	roots := make(BayNodes, 0, 5)
-	for _, node := range bn.nodes {
-		if node.NumParents() != 0 {
-			continue
-			// children := append(children, node.GetChildren()...)
+	for _, node := range nodes {
+		pending[node] = node.NumParents()
+		if node.NumParents() == 0 {
+			roots = append(roots, node)
 		}
-		roots = append(roots, node)
 	}
 
-	id := 1
+	// Kahn's algorithm: a node is ready once all its parents are
+	levels := make([]BayNodes, 0, 5)
 	for len(roots) > 0 {
 		children := make(BayNodes, 0, 10)
 		for _, node := range roots {
-			if node.Id() != 0 {
-				continue
+			for _, child := range node.GetChildren() {
+				if depth[child] < depth[node]+1 {
+					depth[child] = depth[node] + 1
+				}
+				pending[child]--
+				if pending[child] == 0 {
+					children = append(children, child)
+				}
 			}
+		}
+		levels = append(levels, roots)
+		// swap childnodes for parentNodes
+		roots = children
+	}
+
+	byDepth := make([]BayNodes, len(levels))
+	count := 0
+	for _, node := range nodes {
+		if pending[node] != 0 {
+			continue
+		}
+		byDepth[depth[node]] = append(byDepth[depth[node]], node)
+		count++
+	}
+	if count != len(nodes) {
+		return fmt.Errorf("network has a cycle through %d nodes", len(nodes)-count)
+	}
+
+	id := 1
+	for _, level := range byDepth {
+		for _, node := range level {
 			node.setId(id)
 			bn.nodeIndex = append(bn.nodeIndex, node)
 			id++
-			ch := node.GetChildren()
-			children = append(children, ch...)
-
 		}
-		// swap childnodes for parentNodes
-		roots = children
 	}
+	return nil
 }
 
 func (bn *BayesianNetwork) addNode(node *Node) error {
@@ -319,6 +406,32 @@ func (bn *BayesianNetwork) addConnections(parentNames []string, childName string
 	return nil
 }
 
+// name of the network
+func (bn *BayesianNetwork) Name() string {
+	return bn.name
+}
+
+func (bn *BayesianNetwork) SetName(name string) {
+	bn.name = name
+}
+
+// returns the metadata stored under key, or "" if not set
+func (bn *BayesianNetwork) Property(key string) string {
+	return bn.properties[key]
+}
+
+func (bn *BayesianNetwork) SetProperty(key, value string) {
+	if bn.properties == nil {
+		bn.properties = make(map[string]string)
+	}
+	bn.properties[key] = value
+}
+
+// returns all the metadata of the network
+func (bn *BayesianNetwork) Properties() map[string]string {
+	return bn.properties
+}
+
 func (bn *BayesianNetwork) NodeCount() int {
 	return len(bn.nodeIndex)
 }
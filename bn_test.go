@@ -3,7 +3,6 @@ package BayesianNetwork
 import (
 	"fmt"
 	"math"
-	"math/rand"
 	"testing"
 )
 
@@ -24,7 +23,7 @@ func (bn *BayesianNetwork) ValidateIndex(t *testing.T) {
 	for _, node := range bn.GetNodes() {
 		for _, child := range node.childIds {
 			if node.Id() > child.Id() {
-				t.Errorf("Invalid ID on '%s': child '%s' has id ",
+				t.Errorf("Invalid ID on '%s': child '%s' has id %d",
 					node.Name(), child.Name(), child.Id())
 			}
 		}
@@ -33,7 +32,7 @@ func (bn *BayesianNetwork) ValidateIndex(t *testing.T) {
 
 func TestMarkovFig8_2(t *testing.T) {
 
-	rand.Seed(100)
+	Seed(100)
 
 	distRoot := 0.7
 
@@ -182,7 +181,7 @@ func compareStatMaps(stat1, stat2 StatMap, t *testing.T) {
 func TestGibbSampling(t *testing.T) {
 
 	// seed with value for repeatable results
-	rand.Seed(42)
+	Seed(42)
 
 	observations := map[string]string{
 		"J": "T",
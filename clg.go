@@ -0,0 +1,397 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Hybrid networks of binary and conditional linear-Gaussian nodes, see
+// NewCLGNode. Given the binary nodes, the continuous nodes are jointly
+// normal, so the posterior of the continuous nodes is a mixture of
+// normals with one component per assignment of the unobserved binary
+// parents of continuous nodes.
+
+// the largest number of unobserved binary parents of continuous nodes
+// HybridInference enumerates, beyond that it needs samples
+const maxHybridEnumeration = 16
+
+type HybridOptions struct {
+	// the number of importance samples, 0 for exact inference
+	// by enumerating the mixture components
+	Samples int
+}
+
+// A component of the posterior mixture of the continuous nodes
+type MixtureComponent struct {
+	Weight float64
+	// the states of the unobserved binary parents of continuous nodes
+	Assignment map[string]string
+	// the posterior of the unobserved continuous nodes
+	Normal *MultivariateNormal
+}
+
+// The posterior of a hybrid network
+type HybridPosterior struct {
+	// [P(T), P(F)] of every binary node
+	Discrete StatMap
+	// the observed values of continuous nodes
+	Values     map[string]float64
+	Components []*MixtureComponent
+}
+
+// the posterior marginal of a node: [P(T), P(F)] of a binary node
+// and [mean, variance] of a continuous node
+// - an observed continuous node gets [value, 0]
+// - reports an error if the node is not in the posterior
+func (p *HybridPosterior) Marginal(name string) ([]float64, error) {
+	if dist, ok := p.Discrete[name]; ok {
+		return dist, nil
+	}
+	if v, ok := p.Values[name]; ok {
+		return []float64{v, 0}, nil
+	}
+	i := p.index(name)
+	if i < 0 {
+		return nil, fmt.Errorf("Node '%s' is not in the posterior", name)
+	}
+	mean, variance := p.moments(i)
+	return []float64{mean, variance}, nil
+}
+
+// the density of the posterior marginal of an unobserved
+// continuous node at x
+// - reports an error for any other node
+func (p *HybridPosterior) Density(name string, x float64) (float64, error) {
+	i := p.index(name)
+	if i < 0 {
+		return 0, fmt.Errorf("Node '%s' is not an unobserved continuous node", name)
+	}
+	density := 0.0
+	for _, c := range p.Components {
+		m, v := c.Normal.Mean[i], c.Normal.Covariance[i][i]
+		density += c.Weight * math.Exp(-(x-m)*(x-m)/(2*v)) / math.Sqrt(2*math.Pi*v)
+	}
+	return density, nil
+}
+
+// the posterior marginals of every node: [P(T), P(F)] of binary
+// nodes and [mean, variance] of continuous nodes
+func (p *HybridPosterior) Marginals() StatMap {
+	stats := make(StatMap, len(p.Discrete)+len(p.Values))
+	for name, dist := range p.Discrete {
+		stats[name] = dist
+	}
+	for name, v := range p.Values {
+		stats[name] = []float64{v, 0}
+	}
+	if len(p.Components) > 0 {
+		for i, name := range p.Components[0].Normal.Names {
+			mean, variance := p.moments(i)
+			stats[name] = []float64{mean, variance}
+		}
+	}
+	return stats
+}
+
+// the position of an unobserved continuous node in the normals
+// of the components, -1 for any other node
+func (p *HybridPosterior) index(name string) int {
+	if len(p.Components) == 0 {
+		return -1
+	}
+	return indexOf(p.Components[0].Normal.Names, name)
+}
+
+// the mean and variance of the mixture of the i'th variable
+func (p *HybridPosterior) moments(i int) (float64, float64) {
+	mean, second := 0.0, 0.0
+	for _, c := range p.Components {
+		m, v := c.Normal.Mean[i], c.Normal.Covariance[i][i]
+		mean += c.Weight * m
+		second += c.Weight * (v + m*m)
+	}
+	return mean, second - mean*mean
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Computes the posterior of a hybrid network given the states of
+// binary nodes and the values of continuous nodes
+// - with opts.Samples == 0 the mixture is exact: each component is
+//   weighted by the probability of its assignment and the discrete
+//   evidence, and the density of the continuous evidence given them
+// - otherwise the binary nodes are sampled by likelihood weighting, and
+//   every sample is weighted by the exact density of the continuous
+//   evidence given its assignment (Rao-Blackwellized), merging the
+//   samples with the same assignment into one component
+func (bn *BayesianNetwork) HybridInference(evidence map[string]string, values map[string]float64, opts *HybridOptions) (*HybridPosterior, error) {
+	if opts == nil {
+		opts = &HybridOptions{}
+	}
+	if opts.Samples < 0 {
+		return nil, fmt.Errorf("number of samples %d is negative", opts.Samples)
+	}
+	if err := bn.checkContinuousEvidence(values); err != nil {
+		return nil, err
+	}
+	for name := range values {
+		if !bn.nodes[name].IsContinuous() {
+			return nil, fmt.Errorf("%s is binary, its evidence is a state", name)
+		}
+	}
+	discrete, err := bn.discreteNetwork()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := discrete.evidenceBits(evidence); err != nil {
+		return nil, err
+	}
+
+	// the unobserved binary parents of continuous nodes
+	seen := make(map[*Node]bool)
+	var mixing BayNodes
+	for _, node := range bn.nodeIndex {
+		if !node.IsContinuous() {
+			continue
+		}
+		for _, parent := range node.parentIds {
+			_, observed := evidence[parent.Name()]
+			if !parent.IsContinuous() && !observed && !seen[parent] {
+				seen[parent] = true
+				mixing = append(mixing, parent)
+			}
+		}
+	}
+	sort.Sort(mixing)
+
+	if opts.Samples > 0 {
+		return bn.hybridSampling(discrete, evidence, values, mixing, opts.Samples)
+	}
+	if len(mixing) > maxHybridEnumeration {
+		return nil, fmt.Errorf("%d mixing parents exceed %d, use samples", len(mixing), maxHybridEnumeration)
+	}
+
+	var components []*MixtureComponent
+	var logWeights []float64
+	var stats []StatMap
+	for _, key := range cptKeys(len(mixing)) {
+		assignment := make(map[string]string, len(evidence)+len(mixing))
+		for name, state := range evidence {
+			assignment[name] = state
+		}
+		for i, node := range mixing {
+			assignment[node.Name()] = key[i : i+1]
+		}
+
+		logP, err := discrete.LogEvidenceProbability(assignment)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsInf(logP, -1) {
+			continue
+		}
+		mvn, logZ, err := bn.canonical(assignment).condition(values).normal()
+		if err != nil {
+			return nil, err
+		}
+		marginals, err := discrete.ExactInference(assignment)
+		if err != nil {
+			return nil, err
+		}
+
+		components = append(components, &MixtureComponent{
+			Assignment: restrict(assignment, mixing),
+			Normal:     mvn,
+		})
+		logWeights = append(logWeights, logP+logZ)
+		stats = append(stats, marginals)
+	}
+	if len(components) == 0 {
+		return nil, fmt.Errorf("evidence is impossible")
+	}
+
+	z := logSumExp(logWeights...)
+	posterior := &HybridPosterior{
+		Discrete:   make(StatMap),
+		Values:     values,
+		Components: components,
+	}
+	for i, c := range components {
+		c.Weight = math.Exp(logWeights[i] - z)
+		for name, dist := range stats[i] {
+			if posterior.Discrete[name] == nil {
+				posterior.Discrete[name] = make([]float64, 2)
+			}
+			posterior.Discrete[name][0] += c.Weight * dist[0]
+			posterior.Discrete[name][1] += c.Weight * dist[1]
+		}
+	}
+	return posterior, nil
+}
+
+func (bn *BayesianNetwork) hybridSampling(discrete *BayesianNetwork, evidence map[string]string, values map[string]float64, mixing BayNodes, n int) (*HybridPosterior, error) {
+	type cached struct {
+		mvn  *MultivariateNormal
+		logZ float64
+	}
+	normals := make(map[string]*cached)
+
+	components := make(map[string]*MixtureComponent)
+	var keys []string
+	logWeights := make([]float64, n)
+	samples := make([][]bool, n)
+	sampleKeys := make([]string, n)
+
+	key := make([]byte, len(mixing))
+	for s := 0; s < n; s++ {
+		logW := 0.0
+		sample := make([]bool, len(discrete.nodeIndex))
+		for i, node := range discrete.nodeIndex {
+			if state, ok := evidence[node.Name()]; ok {
+				node.SetAssignment(state)
+				logW += math.Log(node.SampleOnCondition(state))
+			} else {
+				node.SetAssignment(node.Sample())
+			}
+			sample[i] = node.GetAssignment() == "T"
+		}
+
+		assignment := make(map[string]string, len(mixing))
+		for i, node := range mixing {
+			state := discrete.nodes[node.Name()].GetAssignment()
+			assignment[node.Name()] = state
+			key[i] = state[0]
+		}
+		k := string(key)
+		if normals[k] == nil {
+			mvn, logZ, err := bn.canonical(assignment).condition(values).normal()
+			if err != nil {
+				discrete.Reset()
+				return nil, err
+			}
+			normals[k] = &cached{mvn, logZ}
+			components[k] = &MixtureComponent{Assignment: assignment, Normal: mvn}
+			keys = append(keys, k)
+		}
+
+		logWeights[s] = logW + normals[k].logZ
+		samples[s] = sample
+		sampleKeys[s] = k
+	}
+	discrete.Reset()
+
+	z := logSumExp(logWeights...)
+	if math.IsInf(z, -1) {
+		return nil, fmt.Errorf("every sample has zero weight")
+	}
+
+	posterior := &HybridPosterior{
+		Discrete: make(StatMap, len(discrete.nodeIndex)),
+		Values:   values,
+	}
+	t := make([]float64, len(discrete.nodeIndex))
+	for s, sample := range samples {
+		w := math.Exp(logWeights[s] - z)
+		components[sampleKeys[s]].Weight += w
+		for i, isTrue := range sample {
+			if isTrue {
+				t[i] += w
+			}
+		}
+	}
+	for i, node := range discrete.nodeIndex {
+		posterior.Discrete[node.Name()] = []float64{t[i], 1 - t[i]}
+	}
+	for _, k := range keys {
+		if components[k].Weight > 0 {
+			posterior.Components = append(posterior.Components, components[k])
+		}
+	}
+	return posterior, nil
+}
+
+// the network of the binary nodes, which cannot
+// have continuous parents
+func (bn *BayesianNetwork) discreteNetwork() (*BayesianNetwork, error) {
+	var nodes BayNodes
+	for _, node := range bn.nodeIndex {
+		if !node.IsContinuous() {
+			nodes = append(nodes, node.clone())
+		}
+	}
+	return buildBayesianNetwork(nodes...)
+}
+
+func restrict(assignment map[string]string, nodes BayNodes) map[string]string {
+	r := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		r[node.Name()] = assignment[node.Name()]
+	}
+	return r
+}
+
+// samples a continuous node from its normal conditional distribution
+// given the assignment of every other node: the density of the node
+// given its parents times the densities of its children, which are
+// linear in the node
+func (bn *BayesianNetwork) sampleContinuous(node *Node) {
+	m, v := node.moments()
+	precision, shift := 1/v, m/v
+	for _, child := range node.childIds {
+		lg := child.linearGaussian()
+		// the child's mean without the node, and the weight of the node
+		rest, weight, i := lg.Intercept, 0.0, 0
+		for _, parent := range child.parentIds {
+			if !parent.IsContinuous() {
+				continue
+			}
+			if parent == node {
+				weight = lg.Weights[i]
+			} else {
+				rest += lg.Weights[i] * parent.Value()
+			}
+			i++
+		}
+		precision += weight * weight / lg.Variance
+		shift += weight * (child.Value() - rest) / lg.Variance
+	}
+	node.SetValue(shift/precision + rng.NormFloat64()/math.Sqrt(precision))
+}
+
+// samples a binary node with continuous children from its conditional
+// distribution given the assignment of every other node
+func (bn *BayesianNetwork) sampleHybrid(node *Node) {
+	weights := make([]float64, 2)
+	for i, state := range []string{"T", "F"} {
+		node.SetAssignment(state)
+		w := node.likelihood()
+		for _, child := range node.childIds {
+			w *= child.likelihood()
+		}
+		weights[i] = w
+	}
+
+	if rng.Float64()*(weights[0]+weights[1]) < weights[0] {
+		node.SetAssignment("T")
+	} else {
+		node.SetAssignment("F")
+	}
+}
+
+// reports whether the node has a continuous child
+func (self *Node) hasContinuousChild() bool {
+	for _, child := range self.childIds {
+		if child.IsContinuous() {
+			return true
+		}
+	}
+	return false
+}
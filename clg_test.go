@@ -0,0 +1,192 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+// the moisture of a lawn depends on rain and the sprinkler, its
+// growth on fertilizer, moisture and temperature
+func BuildLawnNetwork() *BayesianNetwork {
+	return NewBayesianNetwork(
+		NewRootNode("Rain", 0.3),
+		NewNode("Sprinkler", []string{"Rain"}, map[string]float64{"T": 0.1, "F": 0.5}),
+		NewRootNode("Fertilized", 0.5),
+		NewGaussianNode("Temp", nil, 20, nil, 9),
+		NewCLGNode("Moisture", []string{"Rain", "Sprinkler"}, map[string]*LinearGaussian{
+			"TT": {Intercept: 8, Variance: 1},
+			"TF": {Intercept: 6, Variance: 1},
+			"FT": {Intercept: 5, Variance: 1},
+			"FF": {Intercept: 1, Variance: 1},
+		}),
+		NewCLGNode("Growth", []string{"Fertilized", "Moisture", "Temp"}, map[string]*LinearGaussian{
+			"T": {Intercept: 2, Weights: []float64{0.5, 0.1}, Variance: 1},
+			"F": {Intercept: 0, Weights: []float64{0.3, 0.1}, Variance: 1},
+		}),
+	)
+}
+
+func TestCLGNetwork(t *testing.T) {
+	if _, err := buildBayesianNetwork(
+		NewGaussianNode("X", nil, 0, nil, 1),
+		NewNode("A", []string{"X"}, map[string]float64{"T": 0.5, "F": 0.5}),
+	); err == nil {
+		t.Errorf("Expected an error for a binary node with a continuous parent")
+	}
+	if _, err := buildBayesianNetwork(
+		NewRootNode("A", 0.5),
+		NewCLGNode("X", []string{"A"}, map[string]*LinearGaussian{"T": {Variance: 1}}),
+	); err == nil {
+		t.Errorf("Expected an error for a missing linear Gaussian")
+	}
+
+	bn := BuildLawnNetwork()
+	if k := bn.NumParameters(); k != 1+2+1+2+4*2+2*4 {
+		t.Errorf("Exp 22 != %d Act parameters", k)
+	}
+	moisture := bn.GetNode("Moisture")
+	if lg := moisture.GetConditionalLinearGaussian("TF"); lg == nil || lg.Intercept != 6 {
+		t.Errorf("Moisture (TF): Exp intercept 6 != %+v Act", lg)
+	}
+	if lg := moisture.GetLinearGaussian(); lg != nil {
+		t.Errorf("Moisture: Exp no distribution without binary parents != %+v Act", lg)
+	}
+}
+
+func TestHybridInference(t *testing.T) {
+	bn := BuildLawnNetwork()
+
+	// the prior moments of the moisture, by the law of total variance
+	pRS := map[string]float64{"TT": 0.3 * 0.1, "TF": 0.3 * 0.9, "FT": 0.7 * 0.5, "FF": 0.7 * 0.5}
+	mu := map[string]float64{"TT": 8, "TF": 6, "FT": 5, "FF": 1}
+	mean, second := 0.0, 0.0
+	for key, p := range pRS {
+		mean += p * mu[key]
+		second += p * (1 + mu[key]*mu[key])
+	}
+	prior, err := bn.HybridInference(nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prior.Components) != 8 {
+		t.Errorf("Exp 8 != %d Act components", len(prior.Components))
+	}
+	m, err := prior.Marginal("Moisture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(m[0]-mean) > 1e-9 || math.Abs(m[1]-(second-mean*mean)) > 1e-9 {
+		t.Errorf("Moisture: Exp (%.4f, %.4f) != (%.4f, %.4f) Act", mean, second-mean*mean, m[0], m[1])
+	}
+	if r, err := prior.Marginal("Rain"); err != nil || math.Abs(r[0]-0.3) > 1e-9 {
+		t.Errorf("Rain: Exp 0.3 != %v Act (%v)", r, err)
+	}
+	if _, err := prior.Marginal("Unknown"); err == nil {
+		t.Errorf("Expected an error for an unknown node")
+	}
+	if _, err := prior.Density("Rain", 0); err == nil {
+		t.Errorf("Expected an error for the density of a binary node")
+	}
+
+	// observing the moisture weighs each assignment by its density
+	moisture := 5.5
+	z, rain := 0.0, 0.0
+	for key, p := range pRS {
+		w := p * math.Exp(-(moisture-mu[key])*(moisture-mu[key])/2)
+		z += w
+		if key[0] == 'T' {
+			rain += w
+		}
+	}
+	posterior, err := bn.HybridInference(map[string]string{"Fertilized": "T"}, map[string]float64{"Moisture": moisture}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := posterior.Discrete["Rain"][0]; math.Abs(p-rain/z) > 1e-9 {
+		t.Errorf("P(Rain|Moisture): Exp %.6f != %.6f Act", rain/z, p)
+	}
+	if m, err := posterior.Marginal("Growth"); err != nil || math.Abs(m[0]-(2+0.5*moisture+2)) > 1e-9 {
+		t.Errorf("E[Growth|Moisture]: Exp %.4f != %v Act (%v)", 2+0.5*moisture+2, m, err)
+	}
+
+	// importance sampling approaches the exact mixture
+	Seed(42)
+	evidence := map[string]float64{"Growth": 6}
+	exact, err := bn.HybridInference(nil, evidence, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sampled, err := bn.HybridInference(nil, evidence, &HybridOptions{Samples: 20000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareHybrid(t, bn, exact.Marginals(), sampled.Marginals(), 0.03)
+
+	if _, err := bn.HybridInference(nil, map[string]float64{"Rain": 1}, nil); err == nil {
+		t.Errorf("Expected an error for a value of a binary node")
+	}
+}
+
+func TestHybridSampling(t *testing.T) {
+	bn := BuildLawnNetwork()
+	exact, err := bn.HybridInference(nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Seed(42)
+	compareHybrid(t, bn, exact.Marginals(), bn.AncestralSampling(20000), 0.04)
+
+	// Gibbs sampling given the growth. Rain and Moisture are strongly
+	// coupled, so the chain mixes slowly and needs a wider tolerance
+	evidence := map[string]float64{"Growth": 6}
+	exact, err = bn.HybridInference(nil, evidence, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gibbs := bn.GibbsSampling(map[string]string{"Growth": "6"}, 1000, 50000)
+	compareHybrid(t, bn, exact.Marginals(), gibbs, 0.1)
+}
+
+func TestGibbsSamplingEvidence(t *testing.T) {
+	bn := BuildLawnNetwork()
+	for _, evidence := range []map[string]string{
+		{"Growth": "T"},
+		{"Growth": "NaN"},
+		{"Rain": "1.5"},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected a panic for the evidence %v", evidence)
+				}
+			}()
+			bn.GibbsSampling(evidence, 1, 1)
+		}()
+	}
+	if err := bn.UpdateGraphValues(map[string]string{"Rain": "T", "Growth": "x"}); err == nil {
+		t.Errorf("Expected an error for a value that is not a number")
+	}
+	if a := bn.GetNode("Rain").GetAssignment(); a != "" {
+		t.Errorf("Rain: Exp no assignment != '%s' Act", a)
+	}
+}
+
+// compares probabilities within tol, and the means and standard
+// deviations of continuous nodes within tol standard deviations
+func compareHybrid(t *testing.T, bn *BayesianNetwork, exp, act StatMap, tol float64) {
+	for name, dist := range exp {
+		if !bn.GetNode(name).IsContinuous() {
+			if math.Abs(dist[0]-act[name][0]) > tol {
+				t.Errorf("%s: Exp %.4f != %.4f Act", name, dist[0], act[name][0])
+			}
+			continue
+		}
+		sd := math.Sqrt(dist[1])
+		if math.Abs(dist[0]-act[name][0]) > tol*sd+1e-9 {
+			t.Errorf("mean of %s: Exp %.4f != %.4f Act", name, dist[0], act[name][0])
+		}
+		if math.Abs(math.Sqrt(act[name][1])-sd) > tol*sd+1e-9 {
+			t.Errorf("sd of %s: Exp %.4f != %.4f Act", name, sd, math.Sqrt(act[name][1]))
+		}
+	}
+}
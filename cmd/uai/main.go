@@ -0,0 +1,117 @@
+// Command uai runs the inference engines of the BayesianNetwork package
+// on UAI competition instances, following the solver conventions of
+// the UAI 2014 inference competition:
+//
+//	uai [flags] <model.uai> <model.uai.evid> <seed> <task>
+//
+// where task is one of MAR, MPE or PR. The answer is written to
+// <model.uai>.<task> in the current directory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	bn "github.com/paddie/BayesianNetwork"
+)
+
+var (
+	engine  = flag.String("engine", "exact", "inference engine for MAR: exact or gibbs")
+	burnIn  = flag.Int("burnin", 1000, "gibbs: number of sweeps before gathering statistics")
+	samples = flag.Int("samples", 10000, "gibbs: number of sweeps gathering statistics")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] <model.uai> <model.uai.evid> <seed> <MAR|MPE|PR>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 4 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), flag.Arg(1), flag.Arg(2), flag.Arg(3)); err != nil {
+		fmt.Fprintf(os.Stderr, "uai: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(modelPath, evidPath, seedArg, task string) error {
+	if task != "MAR" && task != "MPE" && task != "PR" {
+		return fmt.Errorf("unsupported task '%s', expected MAR, MPE or PR", task)
+	}
+
+	seed, err := strconv.ParseInt(seedArg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid seed '%s'", seedArg)
+	}
+	bn.Seed(seed)
+
+	model, err := os.Open(modelPath)
+	if err != nil {
+		return err
+	}
+	network, order, err := bn.ReadUAI(model)
+	model.Close()
+	if err != nil {
+		return err
+	}
+
+	evid, err := os.Open(evidPath)
+	if err != nil {
+		return err
+	}
+	samples, err := bn.ReadUAIEvidence(evid, order)
+	evid.Close()
+	if err != nil {
+		return err
+	}
+	if len(samples) != 1 {
+		return fmt.Errorf("%s holds %d evidence samples, only one is supported", evidPath, len(samples))
+	}
+	evidence := samples[0]
+
+	out, err := os.Create(filepath.Base(modelPath) + "." + task)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	switch task {
+	case "MAR":
+		stats, err := marginals(network, evidence)
+		if err != nil {
+			return err
+		}
+		return bn.WriteUAIMAR(out, stats, order)
+	case "MPE":
+		assignment, _, err := network.MPE(evidence)
+		if err != nil {
+			return err
+		}
+		return bn.WriteUAIMPE(out, assignment, order)
+	case "PR":
+		logProb, err := network.LogEvidenceProbability(evidence)
+		if err != nil {
+			return err
+		}
+		return bn.WriteUAIPR(out, logProb)
+	}
+	return nil
+}
+
+func marginals(network *bn.BayesianNetwork, evidence map[string]string) (bn.StatMap, error) {
+	switch *engine {
+	case "exact":
+		return network.ExactInference(evidence)
+	case "gibbs":
+		return network.GibbsSampling(evidence, *burnIn, *samples), nil
+	}
+	return nil, fmt.Errorf("unknown engine '%s'", *engine)
+}
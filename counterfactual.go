@@ -0,0 +1,196 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+)
+
+// Counterfactual inference over twin networks.
+//
+// Every non-root node X is given a functional representation
+// X = U(X|pa)[pa]: one exogenous binary noise node per CPT row,
+// U(X|key) ~ Bernoulli(cpt[key]), and X copies the noise of the row
+// selected by its parents. The rows are independent, which is the
+// canonical choice when nothing else is known about the mechanism.
+// Root nodes are their own noise.
+//
+// The twin network holds the factual nodes and a counterfactual copy
+// "X*" of every node affected by the intervention, sharing the same
+// noise nodes. Nodes that are not descendants of an intervened node
+// are identical in both worlds and are not copied.
+// - a node with k parents has 2^k noise nodes and a deterministic
+//   CPT with 2^(k+2^k) rows, keep k small for twinned nodes
+
+// suffix of the counterfactual copy of a node in the twin network
+const counterfactualSuffix = "*"
+
+// name of the noise node of the given CPT row of a node
+func noiseName(name, key string) string {
+	return fmt.Sprintf("U(%s|%s)", name, key)
+}
+
+// Returns the functional representation of the network: every
+// non-root node becomes a deterministic function of its parents
+// and its noise nodes. Marginalizing the noise gives back the
+// original distribution
+func (bn *BayesianNetwork) FunctionalNetwork() *BayesianNetwork {
+	nodes := make(BayNodes, 0, len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		if node.IsRoot() {
+			nodes = append(nodes, node.clone())
+			continue
+		}
+		noise := noiseNodes(node)
+		nodes = append(nodes, noise...)
+		nodes = append(nodes, functionalNode(node, node.Name(), node.GetParentNames(), noise))
+	}
+	return mustBuild(bn, nodes)
+}
+
+// Builds the twin network for the intervention do(X=x), see above.
+// Counterfactual copies are named after the node with a "*" suffix
+// - panics if an intervened node does not exist in the network,
+//   or if a copy collides with the name of an existing node
+func (bn *BayesianNetwork) TwinNetwork(do map[string]string) *BayesianNetwork {
+	affected := bn.affectedBy(do)
+
+	nodes := make(BayNodes, 0, 2*len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		name := node.Name()
+		if !affected[name] {
+			nodes = append(nodes, node.clone())
+			continue
+		}
+
+		twin := name + counterfactualSuffix
+		if bn.nodes[twin] != nil {
+			panic(fmt.Sprintf("counterfactual copy '%s' collides with an existing node", twin))
+		}
+
+		if value, ok := do[name]; ok {
+			nodes = append(nodes, node.clone())
+			p := 0.0
+			if value == "T" {
+				p = 1.0
+			}
+			forced := NewRootNode(twin, p)
+			forced.states = node.states
+			nodes = append(nodes, forced)
+			continue
+		}
+
+		// the factual node and its copy share their noise
+		noise := noiseNodes(node)
+		parents := make([]string, 0, node.NumParents())
+		for _, parent := range node.GetParentNames() {
+			if affected[parent] {
+				parent += counterfactualSuffix
+			}
+			parents = append(parents, parent)
+		}
+
+		nodes = append(nodes, noise...)
+		nodes = append(nodes, functionalNode(node, name, node.GetParentNames(), noise))
+		nodes = append(nodes, functionalNode(node, twin, parents, noise))
+	}
+
+	return mustBuild(bn, nodes)
+}
+
+// Answers the counterfactual query "given the evidence observed in
+// the factual world, what would the marginals have been had the
+// nodes been forced to do" with the given inference method.
+// The result holds the counterfactual marginal of every node
+// e.g. given U=T and P=F, would U have been T had P been T:
+//
+//	bn.Counterfactual(map[string]string{"U": "T", "P": "F"},
+//		map[string]string{"P": "T"}, (*BayesianNetwork).ExactInference)
+func (bn *BayesianNetwork) Counterfactual(evidence, do map[string]string, method InferenceMethod) (StatMap, error) {
+	if _, err := bn.evidenceBits(evidence); err != nil {
+		return nil, err
+	}
+	if _, err := bn.evidenceBits(do); err != nil {
+		return nil, err
+	}
+
+	twin := bn.TwinNetwork(do)
+	affected := bn.affectedBy(do)
+
+	clamped := make(map[string]string, len(evidence)+len(do))
+	for name, value := range evidence {
+		clamped[name] = value
+	}
+	for name, value := range do {
+		clamped[name+counterfactualSuffix] = value
+	}
+
+	stats, err := method(twin, clamped)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(StatMap, len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		name := node.Name()
+		if affected[name] {
+			result[name] = stats[name+counterfactualSuffix]
+		} else {
+			result[name] = stats[name]
+		}
+	}
+	return result, nil
+}
+
+// the intervened nodes and their descendants
+// - panics on invalid interventions
+func (bn *BayesianNetwork) affectedBy(do map[string]string) map[string]bool {
+	affected := make(map[string]bool)
+	for name, value := range do {
+		node := bn.nodes[name]
+		if node == nil {
+			panic(fmt.Sprintf("Node '%s' does not exist in network", name))
+		}
+		if _, err := stateBit(value); err != nil {
+			panic(fmt.Sprintf("%s: %v", name, err))
+		}
+		affected[name] = true
+		for _, d := range bn.descendants(node) {
+			affected[d.Name()] = true
+		}
+	}
+	return affected
+}
+
+// one noise root per CPT row of the node
+func noiseNodes(node *Node) BayNodes {
+	keys := node.rowKeys()
+	noise := make(BayNodes, 0, len(keys))
+	for _, key := range keys {
+		noise = append(noise, NewRootNode(noiseName(node.Name(), key), node.prob(key)))
+	}
+	return noise
+}
+
+// the deterministic node that copies the noise of the row
+// selected by its parents: the parents come first, followed
+// by the noise nodes in row order
+func functionalNode(node *Node, name string, parents []string, noise BayNodes) *Node {
+	k := len(parents)
+	all := make([]string, 0, k+len(noise))
+	all = append(all, parents...)
+	for _, u := range noise {
+		all = append(all, u.Name())
+	}
+
+	f := NewFunctionNode(name, all, func(values []bool) bool {
+		// position of the row selected by the parents
+		row := 0
+		for i := 0; i < k; i++ {
+			if !values[i] {
+				row |= 1 << uint(k-1-i)
+			}
+		}
+		return values[k+row]
+	})
+	f.states = node.states
+	return f
+}
@@ -0,0 +1,59 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFunctionalNetwork(t *testing.T) {
+	bn := BuildStudentNetwork()
+	exp, err := bn.ExactInference(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, err := bn.FunctionalNetwork().ExactInference(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, dist := range exp {
+		if math.Abs(dist[0]-act[name][0]) > 1e-9 {
+			t.Errorf("%s: Exp %.4f != %.4f Act", name, dist[0], act[name][0])
+		}
+	}
+}
+
+// given U=T and P=F, would U have been T had P been T?
+func TestCounterfactual(t *testing.T) {
+	bn := BuildStudentNetwork()
+	evidence := map[string]string{"U": "T", "P": "F"}
+	do := map[string]string{"P": "T"}
+
+	twin := bn.TwinNetwork(do)
+	if twin.GetNode("U*") == nil || twin.GetNode("R*") != nil {
+		t.Fatalf("only the descendants of P should be copied:\n%v", twin.GetNodes())
+	}
+
+	cf, err := bn.Counterfactual(evidence, do, (*BayesianNetwork).ExactInference)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the noise of the row U(U|F,r) is fixed by the evidence, the
+	// counterfactual reads the independent row U(U|T,r):
+	// P(U*=T|e) = sum_r P(R=r|e) P(U=T|P=T,R=r)
+	posterior, err := bn.ExactInference(evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := bn.GetNode("U")
+	exp := posterior["R"][0]*u.cpt["TT"] + posterior["R"][1]*u.cpt["TF"]
+	if math.Abs(cf["U"][0]-exp) > 1e-9 {
+		t.Errorf("P(U*=T|e): Exp %.4f != %.4f Act", exp, cf["U"][0])
+	}
+
+	// nodes that are not affected keep their factual posterior
+	if math.Abs(cf["R"][0]-posterior["R"][0]) > 1e-9 || cf["P"][0] != 1 {
+		t.Errorf("counterfactual R %v P %v", cf["R"], cf["P"])
+	}
+}
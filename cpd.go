@@ -0,0 +1,28 @@
+package BayesianNetwork
+
+// A compact conditional probability distribution of a binary node,
+// used instead of a CPT when the 2^NumParents rows of the table are
+// too many to elicit or to store. The rows are expanded on demand,
+// so every engine that reads the CPT of a node works with any CPD
+// - a CPD must not change once its node is part of a network
+type CPD interface {
+	// P(node=T | parents=key), where the key holds the assignment
+	// of the parents in their order, "" for a node without parents
+	Probability(key string) float64
+	// the number of free parameters of the distribution
+	NumParameters() int
+	// validates the parameters against the number of parents
+	Validate(numParents int) error
+}
+
+// Generate a node whose distribution is given by the CPD
+func NewCPDNode(name string, parents []string, cpd CPD) *Node {
+	node := NewNode(name, parents, nil)
+	node.cpd = cpd
+	return node
+}
+
+// returns the CPD of the node, nil if the node has a CPT
+func (self *Node) GetCPD() CPD {
+	return self.cpd
+}
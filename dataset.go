@@ -0,0 +1,334 @@
+package BayesianNetwork
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// A Dataset holds observations of binary variables, one row per
+// sample and one column per variable. Values are stored as "T"/"F",
+// a missing value as ""
+type Dataset struct {
+	columns []string
+	index   map[string]int
+	rows    [][]string
+}
+
+// Options for reading and writing CSV files
+type CSVOptions struct {
+	// values that mark a missing observation. Defaults to "", "?" and "NA".
+	// the first marker is used when writing
+	Missing []string
+	// maps the values of a column onto "T"/"F",
+	// e.g. {"J": {"hired": "T", "rejected": "F"}}
+	States map[string]map[string]string
+	// if set, the header must name nodes of the network and the
+	// state names of the nodes are accepted and written as values.
+	// The header may also name the numeric columns of the bins stored
+	// with the network, see SetDiscretization
+	Network *BayesianNetwork
+	// maps the numbers of a column onto the nodes of its bins,
+	// see Discretize
+	Discretizations []*Discretization
+	// field delimiter, defaults to ','
+	Comma rune
+}
+
+var defaultMissing = []string{"", "?", "NA"}
+
+// Creates an empty dataset over the given variables
+func NewDataset(columns ...string) *Dataset {
+	ds := &Dataset{
+		columns: columns,
+		index:   make(map[string]int, len(columns)),
+	}
+	for i, name := range columns {
+		ds.index[name] = i
+	}
+	return ds
+}
+
+func (ds *Dataset) Columns() []string {
+	return ds.columns
+}
+
+// number of rows
+func (ds *Dataset) Len() int {
+	return len(ds.rows)
+}
+
+// returns the value of the column in row i, "" if missing
+func (ds *Dataset) Value(i int, column string) string {
+	j, ok := ds.index[column]
+	if !ok {
+		return ""
+	}
+	return ds.rows[i][j]
+}
+
+// returns row i as an evidence map, leaving out missing values
+func (ds *Dataset) Row(i int) map[string]string {
+	row := make(map[string]string, len(ds.columns))
+	for j, value := range ds.rows[i] {
+		if value != "" {
+			row[ds.columns[j]] = value
+		}
+	}
+	return row
+}
+
+// reports whether row i has a missing value
+func (ds *Dataset) IsComplete(i int) bool {
+	for _, value := range ds.rows[i] {
+		if value == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Appends a row given as a column -> "T"/"F" mapping.
+// columns left out of the mapping are missing
+func (ds *Dataset) Append(row map[string]string) error {
+	values := make([]string, len(ds.columns))
+	for name, value := range row {
+		j, ok := ds.index[name]
+		if !ok {
+			return fmt.Errorf("Column '%s' does not exist in dataset", name)
+		}
+		if value != "" && value != "T" && value != "F" {
+			return fmt.Errorf("Invalid value for '%s': '%s' should be T or F", name, value)
+		}
+		values[j] = value
+	}
+	ds.rows = append(ds.rows, values)
+	return nil
+}
+
+// Loads a CSV file whose header names the variables
+// - a numeric column with bins is read as the nodes of
+//   its bins, see CSVOptions.Discretizations
+func ReadCSV(r io.Reader, opts *CSVOptions) (*Dataset, error) {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+
+	reader := csv.NewReader(r)
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("CSV: reading header: %v", err)
+	}
+	return decodeCSV(header, reader.Read, opts)
+}
+
+// maps the records read by next onto a dataset, expanding
+// the discretized columns into the nodes of their bins
+func decodeCSV(header []string, next func() ([]string, error), opts *CSVOptions) (*Dataset, error) {
+	if len(positions(header)) != len(header) {
+		return nil, fmt.Errorf("CSV: duplicate column in header %v", header)
+	}
+	bins, err := opts.discretizations()
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	mappings := make([]map[string]string, len(header))
+	for j, name := range header {
+		if d := bins[name]; d != nil {
+			columns = append(columns, d.Nodes()...)
+			continue
+		}
+		columns = append(columns, name)
+		if mappings[j], err = opts.stateMapping(name); err != nil {
+			return nil, err
+		}
+	}
+
+	ds := NewDataset(columns...)
+	if len(ds.index) != len(columns) {
+		return nil, fmt.Errorf("CSV: duplicate column in %v", columns)
+	}
+
+	missing := opts.missing()
+	for line := 2; ; line++ {
+		record, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV: %v", err)
+		}
+
+		values := make([]string, 0, len(columns))
+		for j, field := range record {
+			d := bins[header[j]]
+			switch {
+			case d != nil && missing[field]:
+				values = append(values, make([]string, len(d.Edges))...)
+			case d != nil:
+				number, err := strconv.ParseFloat(field, 64)
+				if err != nil {
+					return nil, fmt.Errorf("CSV: line %d: '%s' is not a number for '%s'", line, field, header[j])
+				}
+				values = append(values, d.states(number)...)
+			case missing[field]:
+				values = append(values, "")
+			default:
+				value, ok := mappings[j][field]
+				if !ok {
+					return nil, fmt.Errorf("CSV: line %d: unknown value '%s' for '%s'", line, field, header[j])
+				}
+				values = append(values, value)
+			}
+		}
+		ds.rows = append(ds.rows, values)
+	}
+
+	return ds, nil
+}
+
+// Writes the dataset as CSV with a header naming the variables
+func (ds *Dataset) WriteCSV(w io.Writer, opts *CSVOptions) error {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+
+	writer := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		writer.Comma = opts.Comma
+	}
+
+	// invert the state mappings
+	names := make([]map[string]string, len(ds.columns))
+	for j, column := range ds.columns {
+		mapping, err := opts.stateMapping(column)
+		if err != nil {
+			return err
+		}
+		names[j] = map[string]string{"T": "T", "F": "F"}
+		for name, value := range mapping {
+			if name != value {
+				names[j][value] = name
+			}
+		}
+	}
+
+	marker := ""
+	if len(opts.Missing) > 0 {
+		marker = opts.Missing[0]
+	}
+
+	if err := writer.Write(ds.columns); err != nil {
+		return err
+	}
+	record := make([]string, len(ds.columns))
+	for _, row := range ds.rows {
+		for j, value := range row {
+			if value == "" {
+				record[j] = marker
+				continue
+			}
+			record[j] = names[j][value]
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (opts *CSVOptions) missing() map[string]bool {
+	markers := opts.Missing
+	if markers == nil {
+		markers = defaultMissing
+	}
+	missing := make(map[string]bool, len(markers))
+	for _, m := range markers {
+		missing[m] = true
+	}
+	return missing
+}
+
+// the bins of the options and the network by column
+func (opts *CSVOptions) discretizations() (map[string]*Discretization, error) {
+	bins := make(map[string]*Discretization)
+	if opts.Network != nil {
+		stored, err := opts.Network.Discretizations()
+		if err != nil {
+			return nil, fmt.Errorf("CSV: %v", err)
+		}
+		for _, d := range stored {
+			bins[d.Column] = d
+		}
+	}
+	for _, d := range opts.Discretizations {
+		if err := d.validate(); err != nil {
+			return nil, fmt.Errorf("CSV: %v", err)
+		}
+		bins[d.Column] = d
+	}
+	return bins, nil
+}
+
+// returns the mapping from the values of a column onto "T"/"F"
+// - "T"/"F" are always accepted, along with the state names of
+//   the node in the network and the explicit mapping of the column
+func (opts *CSVOptions) stateMapping(column string) (map[string]string, error) {
+	mapping := map[string]string{"T": "T", "F": "F"}
+
+	if opts.Network != nil {
+		node := opts.Network.GetNode(column)
+		if node == nil {
+			return nil, fmt.Errorf("CSV: Node '%s' does not exist in network", column)
+		}
+		states := node.States()
+		mapping[states[0]] = "T"
+		mapping[states[1]] = "F"
+	}
+
+	for name, value := range opts.States[column] {
+		if value != "T" && value != "F" {
+			return nil, fmt.Errorf("CSV: '%s' maps '%s' onto '%s', should be T or F", column, name, value)
+		}
+		mapping[name] = value
+	}
+
+	return mapping, nil
+}
+
+// Draws n ancestral samples from the network into a
+// dataset with one column per node, in index order
+// - panics if the network has continuous nodes, as a
+//   dataset holds "T"/"F" values
+func (bn *BayesianNetwork) SampleDataset(n int) *Dataset {
+	if err := bn.checkDiscrete(); err != nil {
+		panic(err)
+	}
+	columns := make([]string, 0, len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		columns = append(columns, node.Name())
+	}
+	ds := NewDataset(columns...)
+
+	for i := 0; i < n; i++ {
+		row := make([]string, len(bn.nodeIndex))
+		for j, node := range bn.nodeIndex {
+			node.SetAssignment(node.Sample())
+			row[j] = node.GetAssignment()
+		}
+		ds.rows = append(ds.rows, row)
+	}
+	// cleanup
+	bn.Reset()
+
+	return ds
+}
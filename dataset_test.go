@@ -0,0 +1,71 @@
+package BayesianNetwork
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	bn := BuildStudentNetwork()
+	bn.GetNode("J").SetStates("hired", "rejected")
+
+	src := "E,J,U\nT,hired,yes\n?,rejected,no\nF,NA,yes\n"
+	opts := &CSVOptions{
+		Network: bn,
+		States:  map[string]map[string]string{"U": {"yes": "T", "no": "F"}},
+	}
+
+	ds, err := ReadCSV(strings.NewReader(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.Len() != 3 {
+		t.Fatalf("expected 3 rows, got %d", ds.Len())
+	}
+	if ds.Value(0, "J") != "T" || ds.Value(1, "U") != "F" {
+		t.Errorf("state mapping: %v %v", ds.Row(0), ds.Row(1))
+	}
+	if _, ok := ds.Row(1)["E"]; ok || ds.IsComplete(2) {
+		t.Errorf("missing values: %v %v", ds.Row(1), ds.Row(2))
+	}
+
+	var buf bytes.Buffer
+	if err := ds.WriteCSV(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "E,J,U\nT,hired,yes\n,rejected,no\nF,,yes\n"; buf.String() != exp {
+		t.Errorf("WriteCSV:\n%s\nexpected:\n%s", buf.String(), exp)
+	}
+
+	if _, err := ReadCSV(strings.NewReader("E,X\nT,T\n"), opts); err == nil {
+		t.Error("expected an error for a column that is not a node")
+	}
+	if _, err := ReadCSV(strings.NewReader("E\nmaybe\n"), nil); err == nil {
+		t.Error("expected an error for an unknown value")
+	}
+}
+
+func TestSampleDataset(t *testing.T) {
+	bn := BuildStudentNetwork()
+	ds := bn.SampleDataset(10000)
+
+	exp, err := bn.ExactInference(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range ds.Columns() {
+		count := 0
+		for i := 0; i < ds.Len(); i++ {
+			if ds.Value(i, name) == "T" {
+				count++
+			}
+		}
+		act := float64(count) / float64(ds.Len())
+		if math.Abs(act-exp[name][0]) > epsilon {
+			t.Errorf("%s: Exp %.3f != %.3f Act", name, exp[name][0], act)
+		}
+	}
+}
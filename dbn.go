@@ -0,0 +1,362 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Dynamic Bayesian Network (2-slice temporal Bayesian network).
+// The prior slice defines the distribution of slice 0; the
+// transition slice defines slice t given slice t-1. Both slices
+// hold a node per variable under the same name. Transition nodes
+// refer to a variable X of the previous slice as Previous("X"),
+// and to the current slice by the plain name:
+//
+//	prior := BayNodes{NewRootNode("Rain", 0.5), umbrella}
+//	transition := BayNodes{
+//		NewNode("Rain", []string{Previous("Rain")}, ...), umbrella}
+//
+// Queries take one evidence map per time step, nil for a step
+// without observations, and answer with one StatMap per step
+// keyed by the plain variable names.
+// - filtering, smoothing and prediction use the forward-backward
+//   messages over the variables with a child in the next slice,
+//   so the cost is linear in the number of steps
+type DynamicBayesianNetwork struct {
+	// slice 0
+	prior *BayesianNetwork
+	// slice t given slice t-1, with a placeholder
+	// root for every referenced variable of slice t-1
+	transition *BayesianNetwork
+	// the variables of a slice, in the index order of the prior
+	variables []string
+	// the variables with a child in the next slice,
+	// the scope of the forward and backward messages
+	frontier []string
+}
+
+const previousSuffix = "_prev"
+
+// Name of variable X of the previous slice in the transition slice
+func Previous(name string) string {
+	return name + previousSuffix
+}
+
+// Name of variable X of slice t in an unrolled network, e.g. "Rain_3"
+func SliceName(name string, t int) string {
+	return fmt.Sprintf("%s_%d", name, t)
+}
+
+// Creates a dynamic network from the nodes of the prior slice
+// and the nodes of the transition slice
+// - panics if the slices are invalid
+func NewDynamicBayesianNetwork(prior, transition BayNodes) *DynamicBayesianNetwork {
+	dbn, err := buildDynamicBayesianNetwork(prior, transition)
+	if err != nil {
+		panic(err)
+	}
+	return dbn
+}
+
+func buildDynamicBayesianNetwork(prior, transition BayNodes) (*DynamicBayesianNetwork, error) {
+	priorBN, err := buildBayesianNetwork(prior...)
+	if err != nil {
+		return nil, fmt.Errorf("prior slice: %v", err)
+	}
+
+	dbn := &DynamicBayesianNetwork{
+		prior:     priorBN,
+		variables: make([]string, 0, len(prior)),
+	}
+
+	isVariable := make(map[string]bool, len(prior))
+	for _, node := range priorBN.nodeIndex {
+		dbn.variables = append(dbn.variables, node.Name())
+		isVariable[node.Name()] = true
+	}
+	for _, name := range dbn.variables {
+		if isVariable[Previous(name)] {
+			return nil, fmt.Errorf("variable '%s' collides with the previous slice of '%s'", Previous(name), name)
+		}
+	}
+
+	if len(transition) != len(prior) {
+		return nil, fmt.Errorf("transition slice has %d nodes, the prior slice has %d", len(transition), len(prior))
+	}
+
+	referenced := make(map[string]bool)
+	placeholders := make(BayNodes, 0, len(prior))
+	for _, node := range transition {
+		if !isVariable[node.Name()] {
+			return nil, fmt.Errorf("transition node '%s' is not in the prior slice", node.Name())
+		}
+		for _, parent := range node.GetParentNames() {
+			if isVariable[parent] || referenced[parent] {
+				continue
+			}
+			if !strings.HasSuffix(parent, previousSuffix) || !isVariable[strings.TrimSuffix(parent, previousSuffix)] {
+				return nil, fmt.Errorf("parent '%s' of transition node '%s' is not a variable", parent, node.Name())
+			}
+			referenced[parent] = true
+			placeholders = append(placeholders, NewRootNode(parent, 0.5))
+		}
+	}
+
+	dbn.transition, err = buildBayesianNetwork(append(append(BayNodes{}, placeholders...), transition...)...)
+	if err != nil {
+		return nil, fmt.Errorf("transition slice: %v", err)
+	}
+
+	for _, name := range dbn.variables {
+		if referenced[Previous(name)] {
+			dbn.frontier = append(dbn.frontier, name)
+		}
+	}
+	return dbn, nil
+}
+
+// the names of the variables of a slice
+func (dbn *DynamicBayesianNetwork) Variables() []string {
+	return dbn.variables
+}
+
+// Unrolls the network into a regular network over T slices,
+// naming variable X of slice t SliceName(X, t)
+// - panics if T < 1
+func (dbn *DynamicBayesianNetwork) Unroll(T int) *BayesianNetwork {
+	if T < 1 {
+		panic(fmt.Sprintf("cannot unroll %d slices", T))
+	}
+
+	nodes := make(BayNodes, 0, T*len(dbn.variables))
+	for t := 0; t < T; t++ {
+		slice := dbn.prior
+		if t > 0 {
+			slice = dbn.transition
+		}
+		for _, node := range slice.nodeIndex {
+			if slice == dbn.transition && dbn.prior.nodes[node.Name()] == nil {
+				// placeholder of the previous slice
+				continue
+			}
+
+			n := node.clone()
+			n.name = SliceName(node.Name(), t)
+			for i, parent := range n.parentNames {
+				if dbn.prior.nodes[parent] != nil {
+					n.parentNames[i] = SliceName(parent, t)
+				} else {
+					n.parentNames[i] = SliceName(strings.TrimSuffix(parent, previousSuffix), t-1)
+				}
+			}
+			nodes = append(nodes, n)
+		}
+	}
+
+	return mustBuild(dbn.prior, nodes)
+}
+
+// Filtering: the marginals of every slice t given the
+// observations of the slices 0..t
+func (dbn *DynamicBayesianNetwork) Filter(observations []map[string]string) ([]StatMap, error) {
+	bits, err := dbn.observationBits(observations)
+	if err != nil {
+		return nil, err
+	}
+	alpha, err := dbn.forward(bits)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]StatMap, len(bits))
+	for t := range bits {
+		stats[t], err = dbn.sliceMarginals(dbn.sliceFactors(t, bits, previous(alpha, t)), bits[t])
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %v", t, err)
+		}
+	}
+	return stats, nil
+}
+
+// Smoothing: the marginals of every slice given all the observations
+func (dbn *DynamicBayesianNetwork) Smooth(observations []map[string]string) ([]StatMap, error) {
+	bits, err := dbn.observationBits(observations)
+	if err != nil {
+		return nil, err
+	}
+	alpha, err := dbn.forward(bits)
+	if err != nil {
+		return nil, err
+	}
+
+	// beta[t] is proportional to P(e_t+1..T | frontier of slice t)
+	T := len(bits)
+	beta := make([]*factor, T)
+	keep := make([]string, len(dbn.frontier))
+	for i, name := range dbn.frontier {
+		keep[i] = Previous(name)
+	}
+	for t := T - 2; t >= 0; t-- {
+		factors := dbn.sliceFactors(t+1, bits, nil)
+		if beta[t+1] != nil {
+			factors = append(factors, beta[t+1])
+		}
+		b, _ := normalized(marginalize(factors, keep...))
+		beta[t] = b.renamed(func(name string) string {
+			return strings.TrimSuffix(name, previousSuffix)
+		})
+	}
+
+	stats := make([]StatMap, T)
+	for t := range bits {
+		factors := dbn.sliceFactors(t, bits, previous(alpha, t))
+		if beta[t] != nil {
+			factors = append(factors, beta[t])
+		}
+		stats[t], err = dbn.sliceMarginals(factors, bits[t])
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %v", t, err)
+		}
+	}
+	return stats, nil
+}
+
+// Prediction: the marginals of the k slices following the
+// observations, given the observations
+func (dbn *DynamicBayesianNetwork) Predict(observations []map[string]string, k int) ([]StatMap, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	extended := make([]map[string]string, len(observations), len(observations)+k)
+	copy(extended, observations)
+	extended = append(extended, make([]map[string]string, k)...)
+
+	stats, err := dbn.Filter(extended)
+	if err != nil {
+		return nil, err
+	}
+	return stats[len(observations):], nil
+}
+
+// validates the observations and converts them into factor bits
+func (dbn *DynamicBayesianNetwork) observationBits(observations []map[string]string) ([]map[string]int, error) {
+	bits := make([]map[string]int, len(observations))
+	for t, evidence := range observations {
+		b, err := dbn.prior.evidenceBits(evidence)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %v", t, err)
+		}
+		bits[t] = b
+	}
+	return bits, nil
+}
+
+// the forward messages: alpha[t] is the normalized distribution of
+// the unobserved frontier variables of slice t given the
+// observations of the slices 0..t
+func (dbn *DynamicBayesianNetwork) forward(bits []map[string]int) ([]*factor, error) {
+	alpha := make([]*factor, len(bits))
+	for t := range bits {
+		a, z := normalized(marginalize(dbn.sliceFactors(t, bits, previous(alpha, t)), dbn.frontier...))
+		if z == 0 {
+			return nil, fmt.Errorf("step %d: evidence has zero probability", t)
+		}
+		alpha[t] = a
+	}
+	return alpha, nil
+}
+
+// the forward message of the slice before t, nil for slice 0
+func previous(alpha []*factor, t int) *factor {
+	if t == 0 {
+		return nil
+	}
+	return alpha[t-1]
+}
+
+// the CPT factors of slice t reduced by the observations, along
+// with the forward message of slice t-1 over the previous slice
+func (dbn *DynamicBayesianNetwork) sliceFactors(t int, bits []map[string]int, alpha *factor) []*factor {
+	if t == 0 {
+		return dbn.prior.evidenceFactors(bits[0])
+	}
+
+	evidence := copyBits(bits[t])
+	for name, bit := range bits[t-1] {
+		evidence[Previous(name)] = bit
+	}
+
+	factors := make([]*factor, 0, len(dbn.variables)+1)
+	for _, name := range dbn.variables {
+		factors = append(factors, reducedFactor(dbn.transition.nodes[name], evidence))
+	}
+	if alpha != nil {
+		factors = append(factors, alpha.renamed(Previous))
+	}
+	return factors
+}
+
+// the marginals of the variables of a slice from its factors
+func (dbn *DynamicBayesianNetwork) sliceMarginals(factors []*factor, bits map[string]int) (StatMap, error) {
+	stats := make(StatMap, len(dbn.variables))
+	for _, name := range dbn.variables {
+		if bit, ok := bits[name]; ok {
+			stats[name] = []float64{float64(1 - bit), float64(bit)}
+			continue
+		}
+
+		f, z := normalized(marginalize(factors, name))
+		if z == 0 {
+			return nil, fmt.Errorf("evidence has zero probability")
+		}
+		stats[name] = []float64{f.values[0], f.values[1]}
+	}
+	return stats, nil
+}
+
+// sums every variable but keep out of the product of the factors
+// - the factors are not modified
+func marginalize(factors []*factor, keep ...string) *factor {
+	hidden := make(map[string]bool)
+	for _, f := range factors {
+		for _, v := range f.vars {
+			hidden[v] = true
+		}
+	}
+	for _, v := range keep {
+		delete(hidden, v)
+	}
+
+	f, _ := eliminate(append([]*factor{}, factors...), hidden, sumOut)
+	if f == nil {
+		return &factor{values: []float64{1}}
+	}
+	return f
+}
+
+// returns a copy of the factor that sums to one, along with
+// the sum of the factor. A factor summing to zero is returned as is
+func normalized(f *factor) (*factor, float64) {
+	z := 0.0
+	for _, v := range f.values {
+		z += v
+	}
+
+	r := newFactor(f.vars)
+	for i, v := range f.values {
+		r.values[i] = v
+		if z != 0 {
+			r.values[i] /= z
+		}
+	}
+	return r, z
+}
+
+// a copy of the factor with every variable renamed
+func (f *factor) renamed(rename func(string) string) *factor {
+	vars := make([]string, len(f.vars))
+	for i, v := range f.vars {
+		vars[i] = rename(v)
+	}
+	return &factor{vars: vars, values: append([]float64(nil), f.values...)}
+}
@@ -0,0 +1,160 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+// the umbrella world of Russell & Norvig, chapter 15
+func BuildUmbrellaNetwork() *DynamicBayesianNetwork {
+	umbrella := func() *Node {
+		return NewNode("Umbrella", []string{"Rain"}, map[string]float64{"T": 0.9, "F": 0.2})
+	}
+	prior := BayNodes{NewRootNode("Rain", 0.5), umbrella()}
+	transition := BayNodes{
+		NewNode("Rain", []string{Previous("Rain")}, map[string]float64{"T": 0.7, "F": 0.3}),
+		umbrella(),
+	}
+	return NewDynamicBayesianNetwork(prior, transition)
+}
+
+// a slice with an intra-slice edge and two frontier variables
+func BuildMachineNetwork() *DynamicBayesianNetwork {
+	prior := BayNodes{
+		NewRootNode("Worn", 0.1),
+		NewNode("Load", []string{"Worn"}, map[string]float64{"T": 0.6, "F": 0.3}),
+		NewNode("Alarm", []string{"Worn", "Load"}, map[string]float64{
+			"TT": 0.9, "TF": 0.6, "FT": 0.3, "FF": 0.05}),
+	}
+	transition := BayNodes{
+		NewNode("Worn", []string{Previous("Worn"), Previous("Load")}, map[string]float64{
+			"TT": 0.95, "TF": 0.9, "FT": 0.3, "FF": 0.05}),
+		NewNode("Load", []string{"Worn", Previous("Load")}, map[string]float64{
+			"TT": 0.8, "TF": 0.4, "FT": 0.7, "FF": 0.2}),
+		NewNode("Alarm", []string{"Worn", "Load"}, map[string]float64{
+			"TT": 0.9, "TF": 0.6, "FT": 0.3, "FF": 0.05}),
+	}
+	return NewDynamicBayesianNetwork(prior, transition)
+}
+
+func TestUmbrellaFilterAndSmooth(t *testing.T) {
+	dbn := BuildUmbrellaNetwork()
+	observations := []map[string]string{{"Umbrella": "T"}, {"Umbrella": "T"}}
+
+	filtered, err := dbn.Filter(observations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smoothed, err := dbn.Smooth(observations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name string
+		act  float64
+		exp  float64
+	}{
+		{"filtered day 1", filtered[0]["Rain"][0], 0.818},
+		{"filtered day 2", filtered[1]["Rain"][0], 0.883},
+		{"smoothed day 1", smoothed[0]["Rain"][0], 0.883},
+		{"smoothed day 2", smoothed[1]["Rain"][0], 0.883},
+	} {
+		if math.Abs(c.act-c.exp) > 1e-3 {
+			t.Errorf("%s: Exp %.3f != %.3f Act", c.name, c.exp, c.act)
+		}
+	}
+
+	// the prediction converges to the stationary distribution 0.5
+	predicted, err := dbn.Predict(observations, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(predicted) != 20 || math.Abs(predicted[19]["Rain"][0]-0.5) > 1e-3 {
+		t.Errorf("prediction does not converge: %v", predicted[19])
+	}
+}
+
+// every query must agree with exact inference on the unrolled network
+func TestDynamicQueriesMatchUnrolled(t *testing.T) {
+	dbn := BuildMachineNetwork()
+	observations := []map[string]string{
+		{"Alarm": "F"},
+		nil,
+		{"Alarm": "T", "Load": "T"},
+		{"Alarm": "T"},
+		{"Worn": "F"},
+	}
+	T := len(observations)
+
+	// the evidence of the slices 0..n-1 on the unrolled network
+	evidence := func(n int) map[string]string {
+		e := make(map[string]string)
+		for s := 0; s < n; s++ {
+			for name, value := range observations[s] {
+				e[SliceName(name, s)] = value
+			}
+		}
+		return e
+	}
+	compare := func(query string, act StatMap, exp StatMap, s int) {
+		for _, name := range dbn.Variables() {
+			e := exp[SliceName(name, s)][0]
+			if math.Abs(act[name][0]-e) > 1e-9 {
+				t.Errorf("%s: P(%s=T) at step %d: Exp %.6f != %.6f Act", query, name, s, e, act[name][0])
+			}
+		}
+	}
+
+	filtered, err := dbn.Filter(observations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smoothed, err := dbn.Smooth(observations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	predicted, err := dbn.Predict(observations, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unrolled := dbn.Unroll(T + 2)
+	if unrolled.NodeCount() != 3*(T+2) {
+		t.Fatalf("Exp %d != %d Act nodes", 3*(T+2), unrolled.NodeCount())
+	}
+
+	all, err := unrolled.ExactInference(evidence(T))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for s := 0; s < T; s++ {
+		exp, err := unrolled.ExactInference(evidence(s + 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		compare("filter", filtered[s], exp, s)
+		compare("smooth", smoothed[s], all, s)
+	}
+	for k := range predicted {
+		compare("predict", predicted[k], all, T+k)
+	}
+}
+
+func TestInvalidDynamicNetwork(t *testing.T) {
+	prior := BayNodes{NewRootNode("A", 0.5)}
+	for name, transition := range map[string]BayNodes{
+		"unknown variable": {NewNode("A", []string{Previous("B")}, map[string]float64{"T": 1, "F": 0})},
+		"missing slice":    {},
+		"renamed node":     {NewRootNode("B", 0.5)},
+	} {
+		if _, err := buildDynamicBayesianNetwork(prior, transition); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	dbn := BuildUmbrellaNetwork()
+	if _, err := dbn.Filter([]map[string]string{{"Umbrella": "X"}}); err == nil {
+		t.Errorf("expected an error for an invalid observation")
+	}
+}
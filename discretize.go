@@ -0,0 +1,573 @@
+package BayesianNetwork
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Discretization of numeric columns onto binary nodes. A column with k
+// bins is a graded variable: the binary nodes GradeNames(column, k),
+// where the node "column>=j" is T for the values in bin j or above,
+// see GradeNames. Every node keeps its column and lower bin edge as
+// properties, so the bins are written along with the network and new
+// data is mapped the same way, see CSVOptions.Network
+// - the Hugin, XMLBIF and JSON formats keep the properties, the UAI
+//   format has no names or properties
+
+// properties of a node of a discretized column
+const (
+	discretizedColumnProperty = "discretized_column"
+	discretizedEdgeProperty   = "discretized_edge"
+)
+
+type DiscretizeMethod int
+
+const (
+	// bins of equal width between the smallest and largest value
+	EqualWidth DiscretizeMethod = iota
+	// bins with the same number of values
+	EqualFrequency
+	// one bin per component of a Gaussian mixture fitted by EM,
+	// with the edges where the most likely component changes
+	GaussianMixture
+	// recursive splits that minimize the entropy of a class column,
+	// accepted by the MDL criterion of Fayyad and Irani (1993)
+	SupervisedMDL
+)
+
+type DiscretizeOptions struct {
+	Method DiscretizeMethod
+	// the number of bins, defaults to 2. For SupervisedMDL the largest
+	// number of bins, 0 for no limit
+	Bins int
+	// the class column of SupervisedMDL
+	Target string
+	// EM iterations of GaussianMixture, defaults to 100
+	Iterations int
+}
+
+func (opts *DiscretizeOptions) withDefaults() *DiscretizeOptions {
+	o := DiscretizeOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Bins == 0 && o.Method != SupervisedMDL {
+		o.Bins = 2
+	}
+	if o.Iterations == 0 {
+		o.Iterations = 100
+	}
+	return &o
+}
+
+// The bins of a numeric column
+type Discretization struct {
+	Column string
+	// the increasing edges between the bins: bin j > 0 holds the
+	// values in [Edges[j-1], Edges[j]), bin 0 those below Edges[0]
+	Edges []float64
+}
+
+// the number of bins
+func (d *Discretization) Bins() int {
+	return len(d.Edges) + 1
+}
+
+// the bin of the value
+func (d *Discretization) Bin(value float64) int {
+	return sort.Search(len(d.Edges), func(j int) bool { return d.Edges[j] > value })
+}
+
+// the names of the binary nodes of the column, none if
+// the column has a single bin
+func (d *Discretization) Nodes() []string {
+	return GradeNames(d.Column, d.Bins())
+}
+
+// the "T"/"F" states of the nodes for the value
+func (d *Discretization) states(value float64) []string {
+	bin := d.Bin(value)
+	states := make([]string, len(d.Edges))
+	for j := range states {
+		states[j] = "F"
+		if bin > j {
+			states[j] = "T"
+		}
+	}
+	return states
+}
+
+func (d *Discretization) validate() error {
+	for j, edge := range d.Edges {
+		if math.IsNaN(edge) || math.IsInf(edge, 0) {
+			return fmt.Errorf("%s: edge %f is not finite", d.Column, edge)
+		}
+		if j > 0 && edge <= d.Edges[j-1] {
+			return fmt.Errorf("%s: edges %v are not increasing", d.Column, d.Edges)
+		}
+	}
+	return nil
+}
+
+// Fits the bins of a column to its values
+// - classes holds the class of every value for SupervisedMDL,
+//   and is ignored by the other methods
+// - SupervisedMDL leaves a column that does not predict the
+//   classes with a single bin and no nodes
+func Discretize(column string, values []float64, classes []string, opts *DiscretizeOptions) (*Discretization, error) {
+	opts = opts.withDefaults()
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s: no values to discretize", column)
+	}
+	if err := finite(values); err != nil {
+		return nil, fmt.Errorf("%s: %v", column, err)
+	}
+	if opts.Bins < 0 || (opts.Bins == 1 && opts.Method != SupervisedMDL) {
+		return nil, fmt.Errorf("%s: %d bins, expected at least 2", column, opts.Bins)
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	if sorted[0] == sorted[len(sorted)-1] && opts.Method != SupervisedMDL {
+		return nil, fmt.Errorf("%s: every value is %f", column, sorted[0])
+	}
+
+	var edges []float64
+	switch opts.Method {
+	case EqualWidth:
+		edges = equalWidthEdges(sorted, opts.Bins)
+	case EqualFrequency:
+		edges = equalFrequencyEdges(sorted, opts.Bins)
+	case GaussianMixture:
+		edges = mixtureEdges(sorted, opts.Bins, opts.Iterations)
+	case SupervisedMDL:
+		if len(classes) != len(values) {
+			return nil, fmt.Errorf("%s: %d classes for %d values", column, len(classes), len(values))
+		}
+		edges = mdlEdges(values, classes, opts.Bins)
+	default:
+		return nil, fmt.Errorf("%s: unknown discretization method %d", column, opts.Method)
+	}
+
+	d := &Discretization{Column: column, Edges: edges}
+	return d, d.validate()
+}
+
+func equalWidthEdges(sorted []float64, bins int) []float64 {
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	edges := make([]float64, 0, bins-1)
+	for j := 1; j < bins; j++ {
+		edges = append(edges, lo+float64(j)*(hi-lo)/float64(bins))
+	}
+	return edges
+}
+
+// the edges halfway between the values at the quantiles
+// - a quantile inside a run of ties moves to the nearest boundary
+//   between distinct values, but not past the previous edge or the
+//   next quantile; bins only merge when there is no such boundary
+func equalFrequencyEdges(sorted []float64, bins int) []float64 {
+	n := len(sorted)
+	var edges []float64
+	// the edges split sorted[:b] from sorted[b:] for b > prev
+	prev := 0
+	for j := 1; j < bins; j++ {
+		i := j * n / bins
+		next := (j + 1) * n / bins
+		b := -1
+		for d := 0; b < 0; d++ {
+			lo, hi := i-d, i+d
+			if lo <= prev && hi >= next {
+				break
+			}
+			if lo > prev && sorted[lo-1] < sorted[lo] {
+				b = lo
+			} else if hi > prev && hi < next && sorted[hi-1] < sorted[hi] {
+				b = hi
+			}
+		}
+		if b < 0 {
+			continue
+		}
+		edges = append(edges, (sorted[b-1]+sorted[b])/2)
+		prev = b
+	}
+	return edges
+}
+
+// fits a Gaussian mixture by EM, starting from the equal-frequency bins,
+// and returns the points where the most likely component changes
+// - a component that is nowhere the most likely adds no edge
+func mixtureEdges(sorted []float64, k, iterations int) []float64 {
+	n := float64(len(sorted))
+	_, variance := meanVariance(sorted)
+	floor := 1e-6 * variance
+
+	weights := make([]float64, k)
+	means := make([]float64, k)
+	variances := make([]float64, k)
+	for c := range means {
+		weights[c] = 1 / float64(k)
+		means[c] = sorted[(2*c+1)*len(sorted)/(2*k)]
+		variances[c] = variance / float64(k*k)
+	}
+
+	logDensity := func(c int, x float64) float64 {
+		d := x - means[c]
+		return math.Log(weights[c]) - d*d/(2*variances[c]) - math.Log(2*math.Pi*variances[c])/2
+	}
+
+	resp := make([]float64, k)
+	prev := math.Inf(-1)
+	for it := 0; it < iterations; it++ {
+		sum := make([]float64, k)
+		sumX := make([]float64, k)
+		sumXX := make([]float64, k)
+		ll := 0.0
+		for _, x := range sorted {
+			for c := range resp {
+				resp[c] = logDensity(c, x)
+			}
+			z := logSumExp(resp...)
+			ll += z
+			for c := range resp {
+				r := math.Exp(resp[c] - z)
+				sum[c] += r
+				sumX[c] += r * x
+				sumXX[c] += r * x * x
+			}
+		}
+		for c := range means {
+			if sum[c] == 0 {
+				continue
+			}
+			weights[c] = sum[c] / n
+			means[c] = sumX[c] / sum[c]
+			variances[c] = math.Max(sumXX[c]/sum[c]-means[c]*means[c], floor)
+		}
+		if ll-prev < 1e-9*math.Abs(ll) {
+			break
+		}
+		prev = ll
+	}
+
+	order := make([]int, k)
+	for c := range order {
+		order[c] = c
+	}
+	sort.Slice(order, func(i, j int) bool { return means[order[i]] < means[order[j]] })
+
+	// scans the range for the points where the most likely component
+	// changes, and bisects each change
+	best := func(x float64) int {
+		b := order[0]
+		for _, c := range order[1:] {
+			if weights[c] > 0 && logDensity(c, x) > logDensity(b, x) {
+				b = c
+			}
+		}
+		return b
+	}
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	const steps = 1000
+	var edges []float64
+	x, current := lo, best(lo)
+	for s := 1; s <= steps; s++ {
+		next := lo + float64(s)*(hi-lo)/steps
+		if c := best(next); c != current {
+			a, b := x, next
+			for i := 0; i < 60; i++ {
+				m := (a + b) / 2
+				if best(m) == current {
+					a = m
+				} else {
+					b = m
+				}
+			}
+			edges = append(edges, b)
+			current = c
+		}
+		x = next
+	}
+	return edges
+}
+
+func meanVariance(values []float64) (float64, float64) {
+	mean, second := 0.0, 0.0
+	for _, x := range values {
+		mean += x
+		second += x * x
+	}
+	n := float64(len(values))
+	mean /= n
+	return mean, second/n - mean*mean
+}
+
+type mdlInterval struct {
+	// the range of the interval in the sorted values
+	from, to int
+	// the best accepted cut, -1 if there is none
+	cut  int
+	gain float64
+}
+
+// splits the values recursively at the cut that minimizes the class
+// entropy, best gain first, as long as the MDL criterion accepts it
+// and there are fewer than bins bins (0 for no limit)
+func mdlEdges(values []float64, classes []string, bins int) []float64 {
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return values[idx[a]] < values[idx[b]] })
+
+	labels := make(map[string]int)
+	sorted := make([]float64, len(values))
+	class := make([]int, len(values))
+	for i, j := range idx {
+		sorted[i] = values[j]
+		if _, ok := labels[classes[j]]; !ok {
+			labels[classes[j]] = len(labels)
+		}
+		class[i] = labels[classes[j]]
+	}
+
+	split := func(from, to int) *mdlInterval {
+		iv := &mdlInterval{from: from, to: to, cut: -1}
+		iv.cut, iv.gain = mdlCut(sorted, class, len(labels), from, to)
+		return iv
+	}
+
+	intervals := []*mdlInterval{split(0, len(sorted))}
+	var edges []float64
+	for bins == 0 || len(edges) < bins-1 {
+		best := -1
+		for i, iv := range intervals {
+			if iv.cut >= 0 && (best < 0 || iv.gain > intervals[best].gain) {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		iv := intervals[best]
+		edges = append(edges, (sorted[iv.cut-1]+sorted[iv.cut])/2)
+		intervals[best] = split(iv.from, iv.cut)
+		intervals = append(intervals, split(iv.cut, iv.to))
+	}
+	sort.Float64s(edges)
+	return edges
+}
+
+// the cut in [from, to) with the lowest class entropy, and its gain, if
+// the MDL criterion accepts it: a cut at i splits [from, i) from [i, to)
+func mdlCut(sorted []float64, class []int, k, from, to int) (int, float64) {
+	n := to - from
+	total := make([]int, k)
+	for i := from; i < to; i++ {
+		total[class[i]]++
+	}
+
+	left := make([]int, k)
+	right := make([]int, k)
+	cut, best := -1, math.Inf(1)
+	for i := from + 1; i < to; i++ {
+		left[class[i-1]]++
+		if sorted[i] == sorted[i-1] {
+			continue
+		}
+		for c := range right {
+			right[c] = total[c] - left[c]
+		}
+		e := (float64(i-from)*countEntropy(left) + float64(to-i)*countEntropy(right)) / float64(n)
+		if e < best {
+			cut, best = i, e
+		}
+	}
+	if cut < 0 {
+		return -1, 0
+	}
+
+	for c := range left {
+		left[c], right[c] = 0, 0
+	}
+	for i := from; i < to; i++ {
+		if i < cut {
+			left[class[i]]++
+		} else {
+			right[class[i]]++
+		}
+	}
+	ent := countEntropy(total)
+	gain := ent - best
+	delta := math.Log2(math.Pow(3, float64(present(total)))-2) -
+		(float64(present(total))*ent - float64(present(left))*countEntropy(left) - float64(present(right))*countEntropy(right))
+	if gain <= (math.Log2(float64(n-1))+delta)/float64(n) {
+		return -1, 0
+	}
+	return cut, gain
+}
+
+// the entropy in bits of the class counts
+func countEntropy(counts []int) float64 {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	dist := make([]float64, len(counts))
+	for i, c := range counts {
+		dist[i] = float64(c) / float64(n)
+	}
+	return entropy(dist)
+}
+
+// the number of classes with a count
+func present(counts []int) int {
+	k := 0
+	for _, c := range counts {
+		if c > 0 {
+			k++
+		}
+	}
+	return k
+}
+
+// Loads a CSV file with numeric columns, fits the bins of every column
+// in methods to its values and maps the values onto the binary nodes
+// of the bins. The other columns are read as by ReadCSV
+// - missing values are left out of the fit, and a row with a
+//   missing class is left out of the fit of SupervisedMDL
+func DiscretizeCSV(r io.Reader, methods map[string]*DiscretizeOptions, opts *CSVOptions) (*Dataset, []*Discretization, error) {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+
+	reader := csv.NewReader(r)
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("CSV: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("CSV: reading header: %v", io.EOF)
+	}
+	header, records := records[0], records[1:]
+	index := positions(header)
+	missing := opts.missing()
+
+	var columns []string
+	for column := range methods {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	var discretizations []*Discretization
+	for _, column := range columns {
+		method := methods[column].withDefaults()
+		j, ok := index[column]
+		if !ok {
+			return nil, nil, fmt.Errorf("CSV: column '%s' does not exist", column)
+		}
+		target := -1
+		if method.Method == SupervisedMDL {
+			if target, ok = index[method.Target]; !ok {
+				return nil, nil, fmt.Errorf("CSV: class column '%s' of '%s' does not exist", method.Target, column)
+			}
+		}
+
+		var values []float64
+		var classes []string
+		for i, record := range records {
+			if missing[record[j]] || (target >= 0 && missing[record[target]]) {
+				continue
+			}
+			value, err := strconv.ParseFloat(record[j], 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("CSV: line %d: '%s' is not a number for '%s'", i+2, record[j], column)
+			}
+			values = append(values, value)
+			if target >= 0 {
+				classes = append(classes, record[target])
+			}
+		}
+
+		d, err := Discretize(column, values, classes, method)
+		if err != nil {
+			return nil, nil, err
+		}
+		discretizations = append(discretizations, d)
+	}
+
+	mapping := *opts
+	mapping.Discretizations = append(discretizations, opts.Discretizations...)
+	i := 0
+	ds, err := decodeCSV(header, func() ([]string, error) {
+		if i == len(records) {
+			return nil, io.EOF
+		}
+		i++
+		return records[i-1], nil
+	}, &mapping)
+	return ds, discretizations, err
+}
+
+// Stores the bins of a column with the nodes of the column
+// - reports an error if a node of the column is missing
+func (bn *BayesianNetwork) SetDiscretization(d *Discretization) error {
+	if err := d.validate(); err != nil {
+		return err
+	}
+	for j, name := range d.Nodes() {
+		node := bn.nodes[name]
+		if node == nil {
+			return fmt.Errorf("Node '%s' does not exist in network", name)
+		}
+		node.SetProperty(discretizedColumnProperty, d.Column)
+		node.SetProperty(discretizedEdgeProperty, strconv.FormatFloat(d.Edges[j], 'g', -1, 64))
+	}
+	return nil
+}
+
+// Returns the bins stored with the nodes of the network,
+// ordered by column
+func (bn *BayesianNetwork) Discretizations() ([]*Discretization, error) {
+	edges := make(map[string][]float64)
+	var columns []string
+	for _, node := range bn.nodeIndex {
+		column := node.Property(discretizedColumnProperty)
+		if column == "" {
+			continue
+		}
+		edge, err := strconv.ParseFloat(node.Property(discretizedEdgeProperty), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid edge: %v", node.Name(), err)
+		}
+		if _, ok := edges[column]; !ok {
+			columns = append(columns, column)
+		}
+		edges[column] = append(edges[column], edge)
+	}
+	sort.Strings(columns)
+
+	discretizations := make([]*Discretization, 0, len(columns))
+	for _, column := range columns {
+		sort.Float64s(edges[column])
+		d := &Discretization{Column: column, Edges: edges[column]}
+		if err := d.validate(); err != nil {
+			return nil, err
+		}
+		for _, name := range d.Nodes() {
+			if node := bn.nodes[name]; node == nil || node.Property(discretizedColumnProperty) != column {
+				return nil, fmt.Errorf("%s: node '%s' of the bins is missing", column, name)
+			}
+		}
+		discretizations = append(discretizations, d)
+	}
+	return discretizations, nil
+}
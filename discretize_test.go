@@ -0,0 +1,210 @@
+package BayesianNetwork
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiscretize(t *testing.T) {
+	values := []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 10}
+	d, err := Discretize("X", values, nil, &DiscretizeOptions{Method: EqualWidth, Bins: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d.Edges, []float64{2.5, 5, 7.5}) {
+		t.Errorf("equal width: Exp [2.5 5 7.5] != %v Act", d.Edges)
+	}
+	if b := d.Bin(5); b != 2 {
+		t.Errorf("bin of 5: Exp 2 != %d Act", b)
+	}
+	if s := d.states(5); !reflect.DeepEqual(s, []string{"T", "T", "F"}) {
+		t.Errorf("states of 5: Exp [T T F] != %v Act", s)
+	}
+	if names := d.Nodes(); !reflect.DeepEqual(names, []string{"X>=1", "X>=2", "X>=3"}) {
+		t.Errorf("Exp [X>=1 X>=2 X>=3] != %v Act", names)
+	}
+
+	d, err = Discretize("X", values, nil, &DiscretizeOptions{Method: EqualFrequency, Bins: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d.Edges, []float64{1.5, 3.5, 5.5, 7.5}) {
+		t.Errorf("equal frequency: Exp [1.5 3.5 5.5 7.5] != %v Act", d.Edges)
+	}
+
+	// a quantile inside a run of ties moves to the nearest boundary
+	ties := []float64{0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 2}
+	d, err = Discretize("X", ties, nil, &DiscretizeOptions{Method: EqualFrequency, Bins: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d.Edges, []float64{0.5, 1.5}) {
+		t.Errorf("equal frequency with ties: Exp [0.5 1.5] != %v Act", d.Edges)
+	}
+	r := rand.New(rand.NewSource(42))
+	for s := 0; s < 200; s++ {
+		integers := make([]float64, 450)
+		for i := range integers {
+			integers[i] = float64(r.Intn(1001))
+		}
+		d, err = Discretize("X", integers, nil, &DiscretizeOptions{Method: EqualFrequency, Bins: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Edges) != 2 {
+			t.Fatalf("equal frequency of integers: Exp 2 != %d Act edges", len(d.Edges))
+		}
+	}
+
+	// two clusters with equal weights and variances are split halfway
+	var clusters []float64
+	for i := 0; i < 500; i++ {
+		clusters = append(clusters, r.NormFloat64(), 10+r.NormFloat64())
+	}
+	d, err = Discretize("X", clusters, nil, &DiscretizeOptions{Method: GaussianMixture})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Edges) != 1 || math.Abs(d.Edges[0]-5) > 0.5 {
+		t.Errorf("Gaussian mixture: Exp [5] != %v Act", d.Edges)
+	}
+
+	// the class changes at 5.5, and noise does not split
+	var classes, noise []string
+	for i, v := range values {
+		class := "F"
+		if v < 5.5 {
+			class = "T"
+		}
+		classes = append(classes, class)
+		noise = append(noise, bitState(i%2))
+	}
+	d, err = Discretize("X", values, classes, &DiscretizeOptions{Method: SupervisedMDL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d.Edges, []float64{5.5}) {
+		t.Errorf("MDL: Exp [5.5] != %v Act", d.Edges)
+	}
+	d, err = Discretize("X", values, noise, &DiscretizeOptions{Method: SupervisedMDL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Edges) != 0 || len(d.Nodes()) != 0 {
+		t.Errorf("MDL on noise: Exp [] != %v Act", d.Edges)
+	}
+
+	if _, err := Discretize("X", []float64{1, 1}, nil, nil); err == nil {
+		t.Errorf("Expected an error for a constant column")
+	}
+}
+
+// the age of patients depends on the disease, the income does not
+func patientCSV(r *rand.Rand, n int) string {
+	var buf bytes.Buffer
+	buf.WriteString("Sick,Age,Income\n")
+	for i := 0; i < n; i++ {
+		sick := r.Float64() < 0.3
+		age := 30 + 5*r.NormFloat64()
+		if sick {
+			age += 30
+		}
+		income := "NA"
+		if i%10 != 0 {
+			income = fmt.Sprintf("%.0f", 1000*r.Float64())
+		}
+		fmt.Fprintf(&buf, "%s,%.1f,%s\n", map[bool]string{true: "yes", false: "no"}[sick], age, income)
+	}
+	return buf.String()
+}
+
+func TestDiscretizeCSV(t *testing.T) {
+	csv := patientCSV(rand.New(rand.NewSource(42)), 500)
+	opts := &CSVOptions{States: map[string]map[string]string{"Sick": {"yes": "T", "no": "F"}}}
+	ds, bins, err := DiscretizeCSV(strings.NewReader(csv), map[string]*DiscretizeOptions{
+		"Age":    {Method: SupervisedMDL, Target: "Sick"},
+		"Income": {Method: EqualFrequency, Bins: 3},
+	}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if columns := ds.Columns(); !reflect.DeepEqual(columns, []string{"Sick", "Age", "Income>=1", "Income>=2"}) {
+		t.Fatalf("Exp [Sick Age Income>=1 Income>=2] != %v Act", columns)
+	}
+	if age := bins[0]; len(age.Edges) != 1 || math.Abs(age.Edges[0]-45) > 5 {
+		t.Errorf("Age: Exp [45] != %v Act", age.Edges)
+	}
+	if ds.Value(0, "Income>=1") != "" {
+		t.Errorf("Exp a missing income in row 0")
+	}
+
+	// the bins are kept with the network through every format
+	nodes := BayNodes{NewRootNode("Sick", 0.3)}
+	for _, d := range bins {
+		for _, name := range d.Nodes() {
+			nodes = append(nodes, NewRootNode(name, 0.5))
+		}
+	}
+	bn := NewBayesianNetwork(nodes...)
+	for _, d := range bins {
+		if err := bn.SetDiscretization(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var xml, hugin bytes.Buffer
+	if err := bn.WriteXMLBIF(&xml); err != nil {
+		t.Fatal(err)
+	}
+	if err := bn.WriteHugin(&hugin); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(bn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromXML, err := ReadXMLBIF(&xml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromHugin, err := ReadHugin(&hugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON BayesianNetwork
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	for format, decoded := range map[string]*BayesianNetwork{"XMLBIF": fromXML, "Hugin": fromHugin, "JSON": &fromJSON} {
+		stored, err := decoded.Discretizations()
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !reflect.DeepEqual(stored, bins) {
+			t.Errorf("%s: Exp %v != %v Act", format, bins, stored)
+		}
+
+		// new data is mapped by the bins of the network
+		mapped, err := ReadCSV(strings.NewReader(csv), &CSVOptions{
+			States:  opts.States,
+			Network: decoded,
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !reflect.DeepEqual(mapped, ds) {
+			t.Errorf("%s: the data is mapped differently", format)
+		}
+	}
+
+	if _, _, err := DiscretizeCSV(strings.NewReader("A\nx\n"), map[string]*DiscretizeOptions{"A": nil}, nil); err == nil {
+		t.Errorf("Expected an error for a value that is not a number")
+	}
+}
@@ -0,0 +1,201 @@
+package BayesianNetwork
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// Rendering options for WriteDOT
+type DOTOptions struct {
+	// draw the CPT of every node as an HTML table in its label
+	CPT bool
+	// observed nodes are shaded and labelled with their value
+	Evidence map[string]string
+	// posterior marginals, e.g. from GibbsSampling or
+	// ExactInference, annotated on each node
+	Posterior StatMap
+}
+
+// Writes the network as a Graphviz DOT digraph
+// - a nil opts draws the plain DAG
+// - nodes and edges are written in index order, so the
+//   output is stable between runs
+// - continuous nodes are drawn with their linear Gaussians, and
+//   their posterior as [mean, variance]
+func (bn *BayesianNetwork) WriteDOT(w io.Writer, opts *DOTOptions) error {
+	if opts == nil {
+		opts = &DOTOptions{}
+	}
+
+	var buf bytes.Buffer
+
+	name := bn.name
+	if name == "" {
+		name = "BayesianNetwork"
+	}
+	fmt.Fprintf(&buf, "digraph %s {\n", dotQuote(name))
+	buf.WriteString("\tnode [shape=ellipse];\n")
+
+	for _, node := range bn.nodeIndex {
+		attrs := []string{"label=" + opts.label(node)}
+		if opts.CPT {
+			attrs = append(attrs, "shape=plaintext")
+		}
+		if _, ok := opts.Evidence[node.Name()]; ok {
+			attrs = append(attrs, "style=filled", `fillcolor="gray80"`)
+		}
+		fmt.Fprintf(&buf, "\t%s [%s];\n", dotQuote(node.Name()), strings.Join(attrs, ", "))
+	}
+
+	buf.WriteString("\n")
+	for _, node := range bn.nodeIndex {
+		for _, child := range bn.edges[node.Name()] {
+			fmt.Fprintf(&buf, "\t%s -> %s;\n", dotQuote(node.Name()), dotQuote(child))
+		}
+	}
+	buf.WriteString("}\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// builds the label of the node: a quoted string, or an
+// HTML table when the CPT is drawn
+func (opts *DOTOptions) label(node *Node) string {
+	states := node.States()
+
+	lines := []string{node.Name()}
+	if value, ok := opts.Evidence[node.Name()]; ok {
+		state := value
+		if bit, err := stateBit(value); err == nil {
+			state = states[bit]
+		}
+		lines[0] = fmt.Sprintf("%s = %s", node.Name(), state)
+	}
+	if dist, ok := opts.Posterior[node.Name()]; ok {
+		if node.IsContinuous() {
+			lines = append(lines, fmt.Sprintf("mean = %.3f, var = %.3f", dist[0], dist[1]))
+		} else {
+			lines = append(lines, fmt.Sprintf("P(%s) = %.3f", states[0], dist[0]))
+		}
+	}
+
+	if !opts.CPT {
+		return dotQuote(strings.Join(lines, "\n"))
+	}
+	if node.IsContinuous() {
+		return gaussianTable(node, lines)
+	}
+
+	var buf bytes.Buffer
+	parents := node.GetParentNames()
+	cols := len(parents) + 2
+
+	buf.WriteString(`<<TABLE BORDER="0" CELLBORDER="1" CELLSPACING="0">`)
+	for i, line := range lines {
+		bold := html.EscapeString(line)
+		if i == 0 {
+			bold = "<B>" + bold + "</B>"
+		}
+		fmt.Fprintf(&buf, `<TR><TD COLSPAN="%d">%s</TD></TR>`, cols, bold)
+	}
+
+	buf.WriteString("<TR>")
+	for _, parent := range parents {
+		fmt.Fprintf(&buf, "<TD><I>%s</I></TD>", html.EscapeString(parent))
+	}
+	for _, state := range states {
+		fmt.Fprintf(&buf, "<TD><I>%s</I></TD>", html.EscapeString(state))
+	}
+	buf.WriteString("</TR>")
+
+	for _, key := range node.rowKeys() {
+		p := node.prob(key)
+		buf.WriteString("<TR>")
+		if len(parents) > 0 {
+			for j, c := range key {
+				bit, _ := stateBit(string(c))
+				fmt.Fprintf(&buf, "<TD>%s</TD>", html.EscapeString(parentState(node, j, bit)))
+			}
+		}
+		fmt.Fprintf(&buf, "<TD>%.3f</TD><TD>%.3f</TD>", p, 1-p)
+		buf.WriteString("</TR>")
+	}
+	buf.WriteString("</TABLE>>")
+
+	return buf.String()
+}
+
+// draws the linear Gaussians of a continuous node: a row per
+// assignment of the binary parents, with the intercept, the weight
+// of every continuous parent and the variance
+func gaussianTable(node *Node, lines []string) string {
+	var binary, continuous []int
+	for j, parent := range node.parentIds {
+		if parent.IsContinuous() {
+			continuous = append(continuous, j)
+		} else {
+			binary = append(binary, j)
+		}
+	}
+
+	var buf bytes.Buffer
+	cols := len(binary) + len(continuous) + 2
+
+	buf.WriteString(`<<TABLE BORDER="0" CELLBORDER="1" CELLSPACING="0">`)
+	for i, line := range lines {
+		bold := html.EscapeString(line)
+		if i == 0 {
+			bold = "<B>" + bold + "</B>"
+		}
+		fmt.Fprintf(&buf, `<TR><TD COLSPAN="%d">%s</TD></TR>`, cols, bold)
+	}
+
+	buf.WriteString("<TR>")
+	for _, j := range binary {
+		fmt.Fprintf(&buf, "<TD><I>%s</I></TD>", html.EscapeString(node.parentNames[j]))
+	}
+	buf.WriteString("<TD><I>intercept</I></TD>")
+	for _, j := range continuous {
+		fmt.Fprintf(&buf, "<TD><I>%s</I></TD>", html.EscapeString(node.parentNames[j]))
+	}
+	buf.WriteString("<TD><I>variance</I></TD>")
+	buf.WriteString("</TR>")
+
+	for _, key := range cptKeys(len(binary)) {
+		lg := node.gaussians[key]
+		buf.WriteString("<TR>")
+		for i, j := range binary {
+			bit, _ := stateBit(key[i : i+1])
+			fmt.Fprintf(&buf, "<TD>%s</TD>", html.EscapeString(parentState(node, j, bit)))
+		}
+		fmt.Fprintf(&buf, "<TD>%.3f</TD>", lg.Intercept)
+		for _, w := range lg.Weights {
+			fmt.Fprintf(&buf, "<TD>%.3f</TD>", w)
+		}
+		fmt.Fprintf(&buf, "<TD>%.3f</TD>", lg.Variance)
+		buf.WriteString("</TR>")
+	}
+	buf.WriteString("</TABLE>>")
+
+	return buf.String()
+}
+
+// name of the state of the j'th parent of the node
+// - falls back on "T"/"F" before the node is linked
+func parentState(node *Node, j, bit int) string {
+	if j < len(node.parentIds) {
+		return node.parentIds[j].States()[bit]
+	}
+	return bitState(bit)
+}
+
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
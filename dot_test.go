@@ -0,0 +1,63 @@
+package BayesianNetwork
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteDOT(t *testing.T) {
+	bn := BuildStudentNetwork()
+	evidence := map[string]string{"J": "T"}
+	posterior, err := bn.ExactInference(evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	opts := &DOTOptions{CPT: true, Evidence: evidence, Posterior: posterior}
+	if err := bn.WriteDOT(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+
+	for _, edge := range []string{`"E" -> "P"`, `"I" -> "R"`, `"P" -> "U"`, `"R" -> "U"`} {
+		if !strings.Contains(dot, edge) {
+			t.Errorf("missing edge %s in\n%s", edge, dot)
+		}
+	}
+	if !strings.Contains(dot, `"J" [label=<<TABLE`) || !strings.Contains(dot, `fillcolor="gray80"`) {
+		t.Errorf("J should be an observed HTML table:\n%s", dot)
+	}
+	if strings.Count(dot, "fillcolor") != 1 {
+		t.Errorf("only J should be shaded:\n%s", dot)
+	}
+	if !strings.Contains(dot, "<B>J = T</B>") || !strings.Contains(dot, "P(T) = ") {
+		t.Errorf("missing evidence or posterior annotation:\n%s", dot)
+	}
+}
+
+func TestWriteDOTContinuous(t *testing.T) {
+	bn := NewBayesianNetwork(
+		NewRootNode("A", 0.5),
+		NewGaussianNode("X", nil, 1, nil, 4),
+		NewCLGNode("Y", []string{"A", "X"}, map[string]*LinearGaussian{
+			"T": {Intercept: 2, Weights: []float64{0.5}, Variance: 1},
+			"F": {Intercept: -1, Weights: []float64{1.5}, Variance: 2},
+		}),
+	)
+	var buf bytes.Buffer
+	opts := &DOTOptions{CPT: true, Posterior: StatMap{"Y": {1.25, 3}}}
+	if err := bn.WriteDOT(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+
+	row := "<TR><TD>F</TD><TD>-1.000</TD><TD>1.500</TD><TD>2.000</TD></TR>"
+	if !strings.Contains(dot, "<TD><I>intercept</I></TD><TD><I>X</I></TD>") || !strings.Contains(dot, row) {
+		t.Errorf("Y should be drawn with its linear Gaussians:\n%s", dot)
+	}
+	if !strings.Contains(dot, "mean = 1.250, var = 3.000") {
+		t.Errorf("missing the posterior of Y:\n%s", dot)
+	}
+}
@@ -0,0 +1,341 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A conditional independence statement X ⊥ Y | Z
+type Independence struct {
+	X, Y, Z []string
+}
+
+func (ind Independence) String() string {
+	s := fmt.Sprintf("{%s} ⊥ {%s}", strings.Join(ind.X, ", "), strings.Join(ind.Y, ", "))
+	if len(ind.Z) == 0 {
+		return s
+	}
+	return fmt.Sprintf("%s | {%s}", s, strings.Join(ind.Z, ", "))
+}
+
+// Reports whether every node in x is d-separated from every node
+// in y given the observed nodes z, i.e. whether the graph implies
+// x ⊥ y | z
+// - panics if one of the names does not exist in the network
+func (bn *BayesianNetwork) DSeparated(x, y []string, z []string) bool {
+	bn.mustGetNodes(x)
+	bn.mustGetNodes(y)
+	bn.mustGetNodes(z)
+	return bn.dag().dSeparated(x, y, z)
+}
+
+// The DAG of a network as name -> names adjacency, built from
+// bn.edges. Unlike the node links, edges can be removed to
+// reason about mutilated graphs
+type dag struct {
+	parents  map[string][]string
+	children map[string][]string
+}
+
+func (bn *BayesianNetwork) dag() *dag {
+	g := &dag{
+		parents:  make(map[string][]string, len(bn.nodes)),
+		children: make(map[string][]string, len(bn.nodes)),
+	}
+	for parent, children := range bn.edges {
+		for _, child := range children {
+			g.addEdge(parent, child)
+		}
+	}
+	return g
+}
+
+func (g *dag) addEdge(parent, child string) {
+	g.children[parent] = append(g.children[parent], child)
+	g.parents[child] = append(g.parents[child], parent)
+}
+
+// a copy of the graph without the edges for which drop is true
+func (g *dag) without(drop func(parent, child string) bool) *dag {
+	r := &dag{
+		parents:  make(map[string][]string, len(g.parents)),
+		children: make(map[string][]string, len(g.children)),
+	}
+	for parent, children := range g.children {
+		for _, child := range children {
+			if !drop(parent, child) {
+				r.addEdge(parent, child)
+			}
+		}
+	}
+	return r
+}
+
+func (g *dag) dSeparated(x, y, z []string) bool {
+	reachable := g.reachable(x, z)
+	for _, name := range y {
+		if reachable[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// every node reachable by following the given links
+func (g *dag) closure(name string, links map[string][]string) map[string]bool {
+	seen := make(map[string]bool)
+	queue := append([]string{}, links[name]...)
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		queue = append(queue, links[n]...)
+	}
+	return seen
+}
+
+// the nodes reachable from the sources through an active trail
+// given the observed nodes, using the Bayes-ball algorithm
+// (Koller & Friedman, Algorithm 3.1)
+func (g *dag) reachable(sources, observed []string) map[string]bool {
+	inZ := make(map[string]bool, len(observed))
+	for _, name := range observed {
+		inZ[name] = true
+	}
+
+	// phase 1: the observed nodes and their ancestors
+	// - a v-structure is active iff its center is in this set
+	ancestors := make(map[string]bool)
+	queue := append([]string{}, observed...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if ancestors[name] {
+			continue
+		}
+		ancestors[name] = true
+		queue = append(queue, g.parents[name]...)
+	}
+
+	// phase 2: traverse (node, direction) pairs, where up means
+	// the ball arrived from a child and down from a parent
+	type visit struct {
+		name string
+		up   bool
+	}
+
+	reachable := make(map[string]bool)
+	visited := make(map[visit]bool)
+	stack := make([]visit, 0, len(sources))
+	for _, name := range sources {
+		stack = append(stack, visit{name, true})
+	}
+
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[v] {
+			continue
+		}
+		visited[v] = true
+
+		if !inZ[v.name] {
+			reachable[v.name] = true
+		}
+
+		if v.up && !inZ[v.name] {
+			for _, parent := range g.parents[v.name] {
+				stack = append(stack, visit{parent, true})
+			}
+			for _, child := range g.children[v.name] {
+				stack = append(stack, visit{child, false})
+			}
+		} else if !v.up {
+			if !inZ[v.name] {
+				for _, child := range g.children[v.name] {
+					stack = append(stack, visit{child, false})
+				}
+			}
+			if ancestors[v.name] {
+				for _, parent := range g.parents[v.name] {
+					stack = append(stack, visit{parent, true})
+				}
+			}
+		}
+	}
+
+	return reachable
+}
+
+// Lists every active trail between x and y given the observed
+// nodes z. A trail is a path in the undirected skeleton; it is
+// active if every collider on it, or one of its descendants, is
+// observed and every other node on it is unobserved
+// - panics if one of the names does not exist in the network
+func (bn *BayesianNetwork) ActiveTrails(x, y string, z []string) []BayNodes {
+	ends := bn.mustGetNodes([]string{x, y})
+	src, dst := ends[0], ends[1]
+
+	inZ := make(map[*Node]bool, len(z))
+	for _, node := range bn.mustGetNodes(z) {
+		inZ[node] = true
+	}
+	if inZ[src] || inZ[dst] {
+		return nil
+	}
+
+	// colliders are active iff they or a descendant are observed
+	active := make(map[*Node]bool)
+	for node := range inZ {
+		active[node] = true
+		for _, ancestor := range bn.ancestors(node) {
+			active[ancestor] = true
+		}
+	}
+
+	var trails []BayNodes
+	onPath := map[*Node]bool{src: true}
+	path := BayNodes{src}
+
+	var extend func()
+	extend = func() {
+		last := path[len(path)-1]
+		if last == dst {
+			trails = append(trails, append(BayNodes{}, path...))
+			return
+		}
+
+		neighbours := append(append(BayNodes{}, last.GetParents()...), last.GetChildren()...)
+		for _, next := range neighbours {
+			if onPath[next] {
+				continue
+			}
+			// the triple prev - last - next decides if last blocks the trail
+			if len(path) > 1 {
+				prev := path[len(path)-2]
+				collider := isParent(prev, last) && isParent(next, last)
+				if collider && !active[last] || !collider && inZ[last] {
+					continue
+				}
+			}
+
+			onPath[next] = true
+			path = append(path, next)
+			extend()
+			path = path[:len(path)-1]
+			onPath[next] = false
+		}
+	}
+	extend()
+
+	return trails
+}
+
+// reports whether parent -> child is an edge
+func isParent(parent, child *Node) bool {
+	for _, p := range child.GetParents() {
+		if p == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// Local Markov independencies of the graph: every node is independent
+// of its non-descendants given its parents. Every other independence
+// implied by the graph follows from these
+func (bn *BayesianNetwork) LocalIndependencies() []Independence {
+	var independencies []Independence
+	for _, node := range bn.nodeIndex {
+		excluded := map[*Node]bool{node: true}
+		for _, d := range bn.descendants(node) {
+			excluded[d] = true
+		}
+		for _, p := range node.GetParents() {
+			excluded[p] = true
+		}
+
+		var rest []string
+		for _, other := range bn.nodeIndex {
+			if !excluded[other] {
+				rest = append(rest, other.Name())
+			}
+		}
+		if len(rest) == 0 {
+			continue
+		}
+
+		independencies = append(independencies, Independence{
+			X: []string{node.Name()},
+			Y: rest,
+			Z: node.GetParentNames(),
+		})
+	}
+	return independencies
+}
+
+// Enumerates every pairwise independence X ⊥ Y | Z implied by the
+// graph with at most maxConditioning nodes in Z
+// - exponential in maxConditioning, meant for auditing small networks
+func (bn *BayesianNetwork) Independencies(maxConditioning int) []Independence {
+	var independencies []Independence
+	g := bn.dag()
+	nodes := bn.nodeIndex
+	for i, a := range nodes {
+		for _, b := range nodes[i+1:] {
+			others := make(BayNodes, 0, len(nodes)-2)
+			for _, c := range nodes {
+				if c != a && c != b {
+					others = append(others, c)
+				}
+			}
+
+			for _, z := range subsets(others, maxConditioning) {
+				names := make([]string, len(z))
+				for k, node := range z {
+					names[k] = node.Name()
+				}
+				if !g.dSeparated([]string{a.Name()}, []string{b.Name()}, names) {
+					continue
+				}
+				independencies = append(independencies, Independence{
+					X: []string{a.Name()},
+					Y: []string{b.Name()},
+					Z: names,
+				})
+			}
+		}
+	}
+	return independencies
+}
+
+// every subset of nodes with at most max elements, in index order
+func subsets(nodes BayNodes, max int) []BayNodes {
+	result := []BayNodes{{}}
+	for _, node := range nodes {
+		n := len(result)
+		for _, s := range result[:n] {
+			if len(s) < max {
+				result = append(result, append(append(BayNodes{}, s...), node))
+			}
+		}
+	}
+	return result
+}
+
+// the nodes for the given names
+// - panics if one of them does not exist in the network
+func (bn *BayesianNetwork) mustGetNodes(names []string) BayNodes {
+	nodes := make(BayNodes, 0, len(names))
+	for _, name := range names {
+		node := bn.nodes[name]
+		if node == nil {
+			panic(fmt.Sprintf("Node '%s' does not exist in network", name))
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
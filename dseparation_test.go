@@ -0,0 +1,69 @@
+package BayesianNetwork
+
+import (
+	"testing"
+)
+
+func TestDSeparated(t *testing.T) {
+	bn := BuildStudentNetwork()
+
+	cases := []struct {
+		x, y, z []string
+		exp     bool
+	}{
+		{[]string{"E"}, []string{"I"}, nil, true},
+		// observing the collider P, or its descendant J, activates E - P - I
+		{[]string{"E"}, []string{"I"}, []string{"P"}, false},
+		{[]string{"E"}, []string{"I"}, []string{"J"}, false},
+		{[]string{"E"}, []string{"J"}, []string{"P"}, true},
+		{[]string{"J"}, []string{"R"}, nil, false},
+		{[]string{"J"}, []string{"R"}, []string{"I"}, false},
+		{[]string{"J"}, []string{"R"}, []string{"I", "D"}, true},
+		{[]string{"J"}, []string{"R"}, []string{"I", "D", "U"}, false},
+		{[]string{"E"}, []string{"D", "R"}, []string{"I"}, true},
+		{[]string{"E", "J"}, []string{"D", "R"}, []string{"I"}, false},
+	}
+
+	for _, c := range cases {
+		if act := bn.DSeparated(c.x, c.y, c.z); act != c.exp {
+			t.Errorf("DSeparated(%v, %v, %v): Exp %v != %v Act", c.x, c.y, c.z, c.exp, act)
+		}
+	}
+}
+
+func TestActiveTrails(t *testing.T) {
+	bn := BuildStudentNetwork()
+
+	// J - P - U - R is blocked by the unobserved collider U
+	trails := bn.ActiveTrails("J", "R", nil)
+	if len(trails) != 2 {
+		t.Errorf("expected J - P - I - R and J - P - D - R, got %v", trails)
+	}
+
+	trails = bn.ActiveTrails("J", "R", []string{"U", "I"})
+	if len(trails) != 2 {
+		t.Errorf("expected J - P - U - R and J - P - D - R, got %v", trails)
+	}
+}
+
+// every statement enumerated from the graph must be d-separated,
+// and the local Markov independencies must hold
+func TestIndependencies(t *testing.T) {
+	bn := BuildStudentNetwork()
+
+	for _, ind := range append(bn.LocalIndependencies(), bn.Independencies(2)...) {
+		if !bn.DSeparated(ind.X, ind.Y, ind.Z) {
+			t.Errorf("%v does not hold", ind)
+		}
+	}
+
+	found := false
+	for _, ind := range bn.Independencies(0) {
+		if ind.String() == "{E} ⊥ {I}" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("E ⊥ I missing from %v", bn.Independencies(0))
+	}
+}
@@ -0,0 +1,333 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Exact inference by variable elimination.
+// - the network is not modified; node assignments are ignored
+// - intermediate factors are rescaled to their maximum to avoid
+//   underflow on large networks, the scale is kept in log space
+
+// Computes the exact posterior marginals of every node given the
+// evidence, e.g. map[string]string{"J": "T", "E": "F"}
+// - observed nodes get the degenerate marginal of their value
+// - the result has the same layout as GibbsSampling
+func (bn *BayesianNetwork) ExactInference(evidence map[string]string) (StatMap, error) {
+	bits, err := bn.evidenceBits(evidence)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(StatMap, len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		if bit, ok := bits[node.Name()]; ok {
+			stats[node.Name()] = []float64{float64(1 - bit), float64(bit)}
+			continue
+		}
+
+		dist, err := bn.posterior(node.Name(), bits)
+		if err != nil {
+			return nil, err
+		}
+		stats[node.Name()] = dist
+	}
+	return stats, nil
+}
+
+// returns [P(name=T|e), P(name=F|e)]
+func (bn *BayesianNetwork) posterior(name string, evidence map[string]int) ([]float64, error) {
+	factors, auxiliary := bn.sumFactors(evidence)
+	f, _ := eliminate(factors, bn.hidden(evidence, []string{name}, auxiliary...), sumOut)
+	if f == nil {
+		return nil, fmt.Errorf("evidence has zero probability")
+	}
+
+	// the decomposition of noisy-OR nodes can leave tiny
+	// negative values from rounding
+	t, u := math.Max(f.values[0], 0), math.Max(f.values[1], 0)
+	z := t + u
+	if z == 0 {
+		return nil, fmt.Errorf("evidence has zero probability")
+	}
+	return []float64{t / z, u / z}, nil
+}
+
+// Computes the natural logarithm of the probability of the evidence.
+// - returns math.Inf(-1) if the evidence is impossible
+func (bn *BayesianNetwork) LogEvidenceProbability(evidence map[string]string) (float64, error) {
+	bits, err := bn.evidenceBits(evidence)
+	if err != nil {
+		return 0, err
+	}
+
+	factors, auxiliary := bn.sumFactors(bits)
+	f, logScale := eliminate(factors, bn.hidden(bits, nil, auxiliary...), sumOut)
+	if f == nil || f.values[0] <= 0 {
+		return math.Inf(-1), nil
+	}
+	return math.Log(f.values[0]) + logScale, nil
+}
+
+// Computes the most probable explanation: the assignment to every
+// unobserved node with the highest joint probability given the
+// evidence. The returned assignment also contains the evidence,
+// along with the natural logarithm of its joint probability
+func (bn *BayesianNetwork) MPE(evidence map[string]string) (map[string]string, float64, error) {
+	bits, err := bn.evidenceBits(evidence)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	factors := bn.evidenceFactors(bits)
+	hidden := bn.hidden(bits, nil)
+
+	// max-product elimination, remembering the arg-max
+	// table of every eliminated variable for the traceback
+	type step struct {
+		v      string
+		scope  *factor
+		argmax []int
+	}
+	steps := make([]step, 0, len(hidden))
+
+	logScale := 0.0
+	for len(hidden) > 0 {
+		v := nextElimination(factors, hidden)
+		delete(hidden, v)
+
+		var joint *factor
+		factors, joint = takeProduct(factors, v)
+		if joint == nil {
+			continue
+		}
+
+		reduced, argmax := joint.maxOut(v)
+		logScale += reduced.rescale()
+		factors = append(factors, reduced)
+		steps = append(steps, step{v: v, scope: reduced, argmax: argmax})
+	}
+
+	p := 1.0
+	for _, f := range factors {
+		p *= f.values[0]
+	}
+	if p == 0 {
+		return nil, 0, fmt.Errorf("evidence has zero probability")
+	}
+
+	bits = copyBits(bits)
+	for i := len(steps) - 1; i >= 0; i-- {
+		s := steps[i]
+		idx := 0
+		for j, v := range s.scope.vars {
+			idx |= bits[v] << uint(len(s.scope.vars)-1-j)
+		}
+		bits[s.v] = s.argmax[idx]
+	}
+
+	assignment := make(map[string]string, len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		assignment[node.Name()] = bitState(bits[node.Name()])
+	}
+
+	return assignment, math.Log(p) + logScale, nil
+}
+
+// validates the evidence and converts it into factor bits
+func (bn *BayesianNetwork) evidenceBits(evidence map[string]string) (map[string]int, error) {
+	if err := bn.checkDiscrete(); err != nil {
+		return nil, err
+	}
+	bits := make(map[string]int, len(evidence))
+	for name, value := range evidence {
+		if bn.nodes[name] == nil {
+			return nil, fmt.Errorf("Node '%s' does not exist in network", name)
+		}
+		bit, err := stateBit(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		bits[name] = bit
+	}
+	return bits, nil
+}
+
+func copyBits(bits map[string]int) map[string]int {
+	c := make(map[string]int, len(bits))
+	for k, v := range bits {
+		c[k] = v
+	}
+	return c
+}
+
+// the CPT factors of every node reduced by the evidence
+func (bn *BayesianNetwork) evidenceFactors(evidence map[string]int) []*factor {
+	factors := make([]*factor, 0, len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		factors = append(factors, reducedFactor(node, evidence))
+	}
+	return factors
+}
+
+// a CPD with a decomposition into small factors over the node, its
+// parents and an auxiliary variable, valid for sum-product elimination
+// - factors returns nil if the CPD has no decomposition
+type decomposable interface {
+	factors(node *Node) []*factor
+}
+
+// the factors of evidenceFactors for sum-product elimination, where
+// noisy-OR and OR nodes are decomposed into one small factor per
+// parent, along with the auxiliary variables of the decomposition
+// - the tables of the nodes are used by max-product elimination
+func (bn *BayesianNetwork) sumFactors(evidence map[string]int) ([]*factor, []string) {
+	factors := make([]*factor, 0, len(bn.nodeIndex))
+	var auxiliary []string
+	for _, node := range bn.nodeIndex {
+		var decomposition []*factor
+		if cpd, ok := node.cpd.(decomposable); ok {
+			decomposition = cpd.factors(node)
+		}
+		if decomposition == nil {
+			factors = append(factors, reducedFactor(node, evidence))
+			continue
+		}
+		for _, f := range decomposition {
+			factors = append(factors, f.reduce(evidence))
+		}
+		auxiliary = append(auxiliary, auxiliaryName(node.Name()))
+	}
+	return factors, auxiliary
+}
+
+// the set of unobserved nodes, minus the kept query nodes,
+// plus the extra variables to eliminate
+func (bn *BayesianNetwork) hidden(evidence map[string]int, keep []string, extra ...string) map[string]bool {
+	hidden := make(map[string]bool, len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		if _, ok := evidence[node.Name()]; !ok {
+			hidden[node.Name()] = true
+		}
+	}
+	for _, name := range keep {
+		delete(hidden, name)
+	}
+	for _, name := range extra {
+		hidden[name] = true
+	}
+	return hidden
+}
+
+// eliminates the hidden variables using the given
+// marginalization and returns the product of the remaining
+// factors, along with the log of the scale that was divided out
+func eliminate(factors []*factor, hidden map[string]bool, marginalize func(*factor, string) *factor) (*factor, float64) {
+	hidden = copySet(hidden)
+
+	logScale := 0.0
+	for len(hidden) > 0 {
+		v := nextElimination(factors, hidden)
+		delete(hidden, v)
+
+		var joint *factor
+		factors, joint = takeProduct(factors, v)
+		if joint == nil {
+			continue
+		}
+
+		reduced := marginalize(joint, v)
+		logScale += reduced.rescale()
+		factors = append(factors, reduced)
+	}
+
+	if len(factors) == 0 {
+		return nil, logScale
+	}
+
+	result := factors[0]
+	for _, f := range factors[1:] {
+		result = result.product(f)
+	}
+	return result, logScale
+}
+
+func sumOut(f *factor, v string) *factor {
+	return f.sumOut(v)
+}
+
+func copySet(set map[string]bool) map[string]bool {
+	c := make(map[string]bool, len(set))
+	for k, v := range set {
+		c[k] = v
+	}
+	return c
+}
+
+// removes every factor mentioning v from factors and
+// returns the remaining factors and their product
+// - the product is nil if no factor mentions v
+func takeProduct(factors []*factor, v string) ([]*factor, *factor) {
+	var joint *factor
+	rest := factors[:0]
+	for _, f := range factors {
+		if f.position(v) < 0 {
+			rest = append(rest, f)
+			continue
+		}
+		if joint == nil {
+			joint = f
+		} else {
+			joint = joint.product(f)
+		}
+	}
+	return rest, joint
+}
+
+// divides the factor by its largest entry and returns
+// the log of that entry
+func (f *factor) rescale() float64 {
+	max := 0.0
+	for _, v := range f.values {
+		if v > max {
+			max = v
+		}
+	}
+	if max == 0 || max == 1 {
+		return 0
+	}
+	for i := range f.values {
+		f.values[i] /= max
+	}
+	return math.Log(max)
+}
+
+// greedy min-size heuristic: eliminate the variable whose
+// elimination creates the factor with the smallest scope.
+// ties are broken on the name for repeatable results
+func nextElimination(factors []*factor, hidden map[string]bool) string {
+	names := make([]string, 0, len(hidden))
+	for v := range hidden {
+		names = append(names, v)
+	}
+	sort.Strings(names)
+
+	best, bestSize := "", -1
+	for _, v := range names {
+		scope := make(map[string]bool)
+		for _, f := range factors {
+			if f.position(v) < 0 {
+				continue
+			}
+			for _, u := range f.vars {
+				scope[u] = true
+			}
+		}
+		if bestSize < 0 || len(scope) < bestSize {
+			best, bestSize = v, len(scope)
+		}
+	}
+	return best
+}
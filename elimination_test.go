@@ -0,0 +1,102 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+// enumerates every assignment of the network and returns the
+// posterior marginals, the probability of the evidence and the
+// most probable assignment consistent with it
+func bruteForce(bn *BayesianNetwork, evidence map[string]string) (StatMap, float64, map[string]string) {
+	nodes := bn.GetNodes()
+	count := make([]float64, len(nodes))
+	z, best := 0.0, -1.0
+	var mpe map[string]string
+
+	for i := 0; i < 1<<uint(len(nodes)); i++ {
+		consistent := true
+		for j, node := range nodes {
+			value := bitState((i >> uint(j)) & 1)
+			if e, ok := evidence[node.Name()]; ok && e != value {
+				consistent = false
+			}
+			node.SetAssignment(value)
+		}
+		if !consistent {
+			continue
+		}
+
+		p := 1.0
+		for _, node := range nodes {
+			if node.GetAssignment() == "T" {
+				p *= node.P()
+			} else {
+				p *= node.PFalse()
+			}
+		}
+
+		z += p
+		for j, node := range nodes {
+			if node.GetAssignment() == "T" {
+				count[j] += p
+			}
+		}
+		if p > best {
+			best = p
+			mpe = make(map[string]string, len(nodes))
+			for _, node := range nodes {
+				mpe[node.Name()] = node.GetAssignment()
+			}
+		}
+	}
+	bn.Reset()
+
+	stats := make(StatMap, len(nodes))
+	for j, node := range nodes {
+		stats[node.Name()] = []float64{count[j] / z, 1 - count[j]/z}
+	}
+	return stats, z, mpe
+}
+
+func TestExactInference(t *testing.T) {
+	bn := BuildStudentNetwork()
+
+	for _, evidence := range []map[string]string{
+		{},
+		{"J": "T", "E": "T", "I": "F", "D": "F", "R": "F", "U": "T"},
+		{"U": "F"},
+		{"J": "T", "R": "F"},
+	} {
+		exp, z, mpe := bruteForce(bn, evidence)
+
+		act, err := bn.ExactInference(evidence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, dist := range exp {
+			if math.Abs(dist[0]-act[name][0]) > 1e-9 {
+				t.Errorf("%v: %s: Exp %.6f != %.6f Act", evidence, name, dist[0], act[name][0])
+			}
+		}
+
+		logZ, err := bn.LogEvidenceProbability(evidence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(math.Exp(logZ)-z) > 1e-9 {
+			t.Errorf("%v: P(e) Exp %.6f != %.6f Act", evidence, z, math.Exp(logZ))
+		}
+
+		assignment, _, err := bn.MPE(evidence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range mpe {
+			if assignment[name] != value {
+				t.Errorf("%v: MPE Exp %v != %v Act", evidence, mpe, assignment)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+)
+
+// A factor over binary variables used by the exact inference engines.
+// values holds one entry per assignment of vars with the first
+// variable varying slowest and "T" (bit 0) before "F" (bit 1),
+// i.e. the same order as cptKeys
+type factor struct {
+	vars   []string
+	values []float64
+}
+
+func newFactor(vars []string) *factor {
+	return &factor{
+		vars:   vars,
+		values: make([]float64, 1<<uint(len(vars))),
+	}
+}
+
+// the factor P(node | parents) with the scope
+// parents..., node - the node varies fastest
+func nodeFactor(node *Node) *factor {
+	vars := make([]string, 0, len(node.GetParentNames())+1)
+	vars = append(vars, node.GetParentNames()...)
+	vars = append(vars, node.Name())
+
+	f := newFactor(vars)
+	for i, key := range node.rowKeys() {
+		p := node.prob(key)
+		f.values[2*i] = p
+		f.values[2*i+1] = 1 - p
+	}
+	return f
+}
+
+// a CPD with context-specific independence: given the observed
+// parents, the distribution only depends on some of the others
+type contextSpecific interface {
+	// the positions of the unobserved parents the distribution depends
+	// on, where key holds the observed parents and '?' for the others
+	relevant(key []byte) []int
+}
+
+// the factor of the node reduced by the evidence. For a CPD with
+// context-specific independence, the scope only holds the parents
+// the node depends on given the evidence, so the full table of the
+// node is never built
+func reducedFactor(node *Node, evidence map[string]int) *factor {
+	cpd, ok := node.cpd.(contextSpecific)
+	if !ok {
+		return nodeFactor(node).reduce(evidence)
+	}
+
+	key := make([]byte, len(node.parentNames))
+	for i, parent := range node.parentNames {
+		key[i] = '?'
+		if bit, ok := evidence[parent]; ok {
+			key[i] = bitState(bit)[0]
+		}
+	}
+	relevant := cpd.relevant(key)
+
+	vars := make([]string, 0, len(relevant)+1)
+	for _, pos := range relevant {
+		vars = append(vars, node.parentNames[pos])
+	}
+	vars = append(vars, node.Name())
+
+	// the other unobserved parents do not matter
+	row := make([]byte, len(key))
+	for i, b := range key {
+		row[i] = b
+		if b == '?' {
+			row[i] = 'T'
+		}
+	}
+	f := newFactor(vars)
+	for idx := 0; idx < 1<<uint(len(relevant)); idx++ {
+		for j, pos := range relevant {
+			row[pos] = bitState(bitOf(idx, j, len(relevant)))[0]
+		}
+		p := node.prob(string(row))
+		f.values[2*idx] = p
+		f.values[2*idx+1] = 1 - p
+	}
+	return f.reduce(evidence)
+}
+
+// returns the position of v in the scope, or -1
+func (f *factor) position(v string) int {
+	for i, name := range f.vars {
+		if name == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// bit of variable at position pos in the index of
+// a factor over n variables
+func bitOf(idx, pos, n int) int {
+	return (idx >> uint(n-1-pos)) & 1
+}
+
+// maps every index of the scope vars onto an index of f
+// - variables of vars not in f are ignored
+func (f *factor) projection(vars []string) []int {
+	n, m := len(vars), len(f.vars)
+	pos := make([]int, n)
+	for j, v := range vars {
+		pos[j] = f.position(v)
+	}
+
+	proj := make([]int, 1<<uint(n))
+	for idx := range proj {
+		fi := 0
+		for j := 0; j < n; j++ {
+			if pos[j] < 0 {
+				continue
+			}
+			fi |= bitOf(idx, j, n) << uint(m-1-pos[j])
+		}
+		proj[idx] = fi
+	}
+	return proj
+}
+
+// pointwise product of two factors
+func (f *factor) product(g *factor) *factor {
+	vars := make([]string, 0, len(f.vars)+len(g.vars))
+	vars = append(vars, f.vars...)
+	for _, v := range g.vars {
+		if f.position(v) < 0 {
+			vars = append(vars, v)
+		}
+	}
+
+	r := newFactor(vars)
+	pf, pg := f.projection(vars), g.projection(vars)
+	for idx := range r.values {
+		r.values[idx] = f.values[pf[idx]] * g.values[pg[idx]]
+	}
+	return r
+}
+
+// scope of f without the variable v
+func (f *factor) without(v string) []string {
+	vars := make([]string, 0, len(f.vars))
+	for _, name := range f.vars {
+		if name != v {
+			vars = append(vars, name)
+		}
+	}
+	return vars
+}
+
+// sums the variable v out of the factor
+func (f *factor) sumOut(v string) *factor {
+	r := newFactor(f.without(v))
+	proj := r.projection(f.vars)
+	for idx, value := range f.values {
+		r.values[proj[idx]] += value
+	}
+	return r
+}
+
+// maxes the variable v out of the factor and returns, for
+// every entry of the result, the value of v (0 = T, 1 = F)
+// that attained the maximum
+func (f *factor) maxOut(v string) (*factor, []int) {
+	r := newFactor(f.without(v))
+	argmax := make([]int, len(r.values))
+	seen := make([]bool, len(r.values))
+
+	pos := f.position(v)
+	proj := r.projection(f.vars)
+	for idx, value := range f.values {
+		ri := proj[idx]
+		if !seen[ri] || value > r.values[ri] {
+			r.values[ri] = value
+			argmax[ri] = bitOf(idx, pos, len(f.vars))
+			seen[ri] = true
+		}
+	}
+	return r, argmax
+}
+
+// restricts the factor to the observed values in evidence,
+// removing the observed variables from the scope
+func (f *factor) reduce(evidence map[string]int) *factor {
+	vars := make([]string, 0, len(f.vars))
+	for _, v := range f.vars {
+		if _, ok := evidence[v]; !ok {
+			vars = append(vars, v)
+		}
+	}
+	if len(vars) == len(f.vars) {
+		return f
+	}
+
+	r := newFactor(vars)
+	proj := r.projection(f.vars)
+	for idx, value := range f.values {
+		consistent := true
+		for j, v := range f.vars {
+			if e, ok := evidence[v]; ok && bitOf(idx, j, len(f.vars)) != e {
+				consistent = false
+				break
+			}
+		}
+		if consistent {
+			r.values[proj[idx]] = value
+		}
+	}
+	return r
+}
+
+func (f *factor) String() string {
+	return fmt.Sprintf("factor%v%v", f.vars, f.values)
+}
+
+// converts a "T"/"F" assignment into the bit used by factors
+func stateBit(assignment string) (int, error) {
+	switch assignment {
+	case "T":
+		return 0, nil
+	case "F":
+		return 1, nil
+	}
+	return 0, fmt.Errorf("Invalid assignment: '%s' should be T or F", assignment)
+}
+
+func bitState(bit int) string {
+	if bit == 0 {
+		return "T"
+	}
+	return "F"
+}
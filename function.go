@@ -0,0 +1,323 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Deterministic nodes: the value of the node is a function of the
+// values of its parents, e.g. a logical gate. The node is a CPD with
+// the probabilities 0 and 1, so the exact engines handle it as any
+// other node, while GibbsSampling resamples it jointly with the
+// nodes it depends on, see gibbsBlocks.
+
+// A deterministic function of the parent values, in the order of the
+// parents, where true is the state "T"
+type Function func(parents []bool) bool
+
+type function struct {
+	// name of the function, used in errors
+	kind string
+	f    Function
+	// validates the number of parents, nil if any number is valid
+	arity func(numParents int) error
+}
+
+// Generate a node whose value is f of the values of its parents
+func NewFunctionNode(name string, parents []string, f Function) *Node {
+	return NewCPDNode(name, parents, &function{kind: "function", f: f})
+}
+
+// Generate a node that is T iff every parent is T
+func NewAndNode(name string, parents []string) *Node {
+	return NewCPDNode(name, parents, &function{kind: "AND", f: func(values []bool) bool {
+		for _, v := range values {
+			if !v {
+				return false
+			}
+		}
+		return true
+	}})
+}
+
+// Generate a node that is T iff at least one parent is T
+func NewOrNode(name string, parents []string) *Node {
+	return NewCPDNode(name, parents, &function{kind: "OR", f: func(values []bool) bool {
+		for _, v := range values {
+			if v {
+				return true
+			}
+		}
+		return false
+	}})
+}
+
+// Generate a node that is T iff an odd number of parents are T
+func NewXorNode(name string, parents []string) *Node {
+	return NewCPDNode(name, parents, &function{kind: "XOR", f: func(values []bool) bool {
+		odd := false
+		for _, v := range values {
+			odd = odd != v
+		}
+		return odd
+	}})
+}
+
+// Generate a node that is T iff at least k of its parents are T
+func NewKOfNNode(name string, parents []string, k int) *Node {
+	return NewCPDNode(name, parents, &function{
+		kind: fmt.Sprintf("%d-of-n", k),
+		f: func(values []bool) bool {
+			count := 0
+			for _, v := range values {
+				if v {
+					count++
+				}
+			}
+			return count >= k
+		},
+		arity: func(numParents int) error {
+			if k < 0 || k > numParents {
+				return fmt.Errorf("k = %d is not in [0, %d]", k, numParents)
+			}
+			return nil
+		},
+	})
+}
+
+func (fn *function) Probability(key string) float64 {
+	values := make([]bool, len(key))
+	for i := range values {
+		values[i] = key[i] == 'T'
+	}
+	if fn.f(values) {
+		return 1
+	}
+	return 0
+}
+
+func (fn *function) NumParameters() int {
+	return 0
+}
+
+func (fn *function) Validate(numParents int) error {
+	if fn.f == nil {
+		return fmt.Errorf("%s node has no function", fn.kind)
+	}
+	if fn.arity != nil {
+		if err := fn.arity(numParents); err != nil {
+			return fmt.Errorf("%s node: %v", fn.kind, err)
+		}
+	}
+	return nil
+}
+
+// an OR node is a noisy-OR node with certain links and no leak,
+// so it has the same decomposition for sum-product elimination
+func (fn *function) factors(node *Node) []*factor {
+	if fn.kind != "OR" {
+		return nil
+	}
+	links := make([]float64, len(node.parentNames))
+	for i := range links {
+		links[i] = 1
+	}
+	return (&noisyOR{links: links}).factors(node)
+}
+
+// Reports whether the value of the node is determined by its
+// parents: a function node, or a CPT with only 0 and 1 entries
+// - other CPDs are not inspected, and continuous nodes are not
+//   deterministic
+func (self *Node) IsDeterministic() bool {
+	if self.gaussians != nil {
+		return false
+	}
+	if self.cpd != nil {
+		_, ok := self.cpd.(*function)
+		return ok
+	}
+	for _, p := range self.cpt {
+		if p != 0 && p != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// the value of a deterministic node given the assignment of its parents
+func (self *Node) deterministicValue() string {
+	if self.CPT() == 1 {
+		return "T"
+	}
+	return "F"
+}
+
+// the largest number of stochastic nodes sampled as one block;
+// larger blocks are sampled one node at a time
+const maxGibbsBlock = 12
+
+// A set of unobserved nodes that GibbsSampling samples jointly from
+// their conditional distribution given every other node: the
+// stochastic nodes that deterministic nodes depend on, together with
+// the unobserved deterministic nodes computed from them. Sampling the
+// parents of a deterministic node one at a time would get stuck, as
+// every single change would contradict the value of the node
+type gibbsBlock struct {
+	// the stochastic nodes, every assignment of them is enumerated
+	free BayNodes
+	// the unobserved deterministic nodes, in index order
+	computed BayNodes
+	// the nodes whose probability depends on the block
+	family BayNodes
+}
+
+// Splits the unobserved nodes into the blocks of the deterministic
+// nodes and the stochastic nodes that are sampled on their own
+func (bn *BayesianNetwork) gibbsBlocks(unobserved BayNodes) ([]*gibbsBlock, BayNodes) {
+	isUnobserved := make(map[*Node]bool, len(unobserved))
+	for _, node := range unobserved {
+		isUnobserved[node] = true
+	}
+
+	// the unobserved stochastic nodes every deterministic node depends
+	// on, through chains of unobserved deterministic parents
+	support := make(map[*Node]BayNodes)
+	for _, node := range bn.nodeIndex {
+		if !node.IsDeterministic() {
+			continue
+		}
+		var nodes BayNodes
+		for _, parent := range node.GetParents() {
+			switch {
+			case !isUnobserved[parent]:
+			case parent.IsDeterministic():
+				nodes = append(nodes, support[parent]...)
+			default:
+				nodes = append(nodes, parent)
+			}
+		}
+		support[node] = nodes
+	}
+
+	// union-find: the support of a deterministic node is one block
+	leader := make(map[*Node]*Node)
+	var find func(*Node) *Node
+	find = func(node *Node) *Node {
+		l, ok := leader[node]
+		if !ok || l == node {
+			return node
+		}
+		leader[node] = find(l)
+		return leader[node]
+	}
+	for _, nodes := range support {
+		for _, node := range nodes {
+			leader[find(node)] = find(nodes[0])
+		}
+	}
+
+	blocks := make(map[*Node]*gibbsBlock)
+	var ordered []*gibbsBlock
+	block := func(node *Node) *gibbsBlock {
+		l := find(node)
+		if blocks[l] == nil {
+			blocks[l] = &gibbsBlock{}
+			ordered = append(ordered, blocks[l])
+		}
+		return blocks[l]
+	}
+
+	var singles BayNodes
+	for _, node := range unobserved {
+		_, inBlock := leader[node]
+		switch {
+		case node.IsDeterministic() && len(support[node]) > 0:
+			b := block(support[node][0])
+			b.computed = append(b.computed, node)
+		case node.IsDeterministic():
+			// constant given the evidence
+			b := block(node)
+			b.computed = append(b.computed, node)
+		case inBlock:
+			b := block(node)
+			b.free = append(b.free, node)
+		default:
+			singles = append(singles, node)
+		}
+	}
+
+	for _, b := range ordered {
+		if len(b.free) > maxGibbsBlock {
+			singles = append(singles, b.free...)
+			b.free = nil
+		}
+		b.family = blockFamily(b)
+	}
+	return ordered, singles
+}
+
+// the nodes of the block and their children, in index order
+func blockFamily(b *gibbsBlock) BayNodes {
+	seen := make(map[*Node]bool)
+	var family BayNodes
+	add := func(node *Node) {
+		if !seen[node] {
+			seen[node] = true
+			family = append(family, node)
+		}
+	}
+	for _, nodes := range []BayNodes{b.free, b.computed} {
+		for _, node := range nodes {
+			add(node)
+			for _, child := range node.GetChildren() {
+				add(child)
+			}
+		}
+	}
+	sort.Sort(family)
+	return family
+}
+
+// samples the block from its conditional distribution given the
+// assignment of every other node
+// - if every assignment of the block has zero probability, e.g.
+//   from the initial assignment, one is chosen uniformly
+func (bn *BayesianNetwork) sampleBlock(b *gibbsBlock) {
+	weights := make([]float64, 1<<uint(len(b.free)))
+	z := 0.0
+	for i := range weights {
+		b.assign(i)
+		w := 1.0
+		for _, node := range b.family {
+			w *= node.likelihood()
+		}
+		weights[i] = w
+		z += w
+	}
+
+	if z == 0 {
+		b.assign(rng.Intn(len(weights)))
+		return
+	}
+	r := rng.Float64() * z
+	for i, w := range weights {
+		r -= w
+		if r < 0 || i == len(weights)-1 {
+			b.assign(i)
+			return
+		}
+	}
+}
+
+// assigns the i'th assignment of the stochastic nodes, in cptKeys
+// order, and computes the deterministic nodes from it
+func (b *gibbsBlock) assign(i int) {
+	n := len(b.free)
+	for j, node := range b.free {
+		node.SetAssignment(bitState(bitOf(i, j, n)))
+	}
+	for _, node := range b.computed {
+		node.SetAssignment(node.deterministicValue())
+	}
+}
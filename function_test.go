@@ -0,0 +1,106 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFunctionNodes(t *testing.T) {
+	parents := []string{"A", "B", "C"}
+	for _, test := range []struct {
+		node *Node
+		exp  map[string]float64
+	}{
+		{NewAndNode("X", parents), map[string]float64{"TTT": 1, "TTF": 0, "FFF": 0}},
+		{NewOrNode("X", parents), map[string]float64{"TTT": 1, "FFT": 1, "FFF": 0}},
+		{NewXorNode("X", parents), map[string]float64{"TTT": 1, "TTF": 0, "FFT": 1}},
+		{NewKOfNNode("X", parents, 2), map[string]float64{"TTT": 1, "TFT": 1, "FFT": 0}},
+	} {
+		for key, p := range test.exp {
+			if act := test.node.prob(key); act != p {
+				t.Errorf("%s(%s): Exp %.0f != %.0f Act", test.node.cpd.(*function).kind, key, p, act)
+			}
+		}
+		if !test.node.IsDeterministic() {
+			t.Errorf("%s should be deterministic", test.node.cpd.(*function).kind)
+		}
+	}
+
+	if err := NewKOfNNode("X", parents, 4).validateCPT(3); err == nil {
+		t.Errorf("Expected an error for k > n")
+	}
+	if err := NewFunctionNode("X", parents, nil).validateCPT(3); err == nil {
+		t.Errorf("Expected an error for a missing function")
+	}
+	if NewNode("X", []string{"A"}, map[string]float64{"T": 1, "F": 0.5}).IsDeterministic() {
+		t.Errorf("a CPT with 0.5 is not deterministic")
+	}
+}
+
+// two faults, an alarm that fires if either is present and
+// a parity check of the faults, with a noisy sensor on A
+func BuildAlarmNetwork() *BayesianNetwork {
+	return NewBayesianNetwork(
+		NewRootNode("A", 0.3),
+		NewRootNode("B", 0.6),
+		NewOrNode("Alarm", []string{"A", "B"}),
+		NewXorNode("Parity", []string{"A", "B"}),
+		NewNode("Sensor", []string{"A"}, map[string]float64{"T": 0.9, "F": 0.2}),
+	)
+}
+
+func TestFunctionNodeExactInference(t *testing.T) {
+	bn := BuildAlarmNetwork()
+	for _, evidence := range []map[string]string{
+		{},
+		{"Alarm": "T"},
+		{"Parity": "T", "Sensor": "T"},
+		{"Alarm": "F", "Sensor": "T"},
+	} {
+		exp, z, _ := bruteForce(bn, evidence)
+		act, err := bn.ExactInference(evidence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, dist := range exp {
+			if math.Abs(dist[0]-act[name][0]) > 1e-9 {
+				t.Errorf("%v: %s: Exp %.6f != %.6f Act", evidence, name, dist[0], act[name][0])
+			}
+		}
+		logP, err := bn.LogEvidenceProbability(evidence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(logP-math.Log(z)) > 1e-9 {
+			t.Errorf("%v: log P(e): Exp %.6f != %.6f Act", evidence, math.Log(z), logP)
+		}
+	}
+
+	// the parity is zero without the alarm
+	if _, err := bn.ExactInference(map[string]string{"Alarm": "F", "Parity": "T"}); err == nil {
+		t.Errorf("Expected an error for impossible evidence")
+	}
+}
+
+func TestFunctionNodeGibbsSampling(t *testing.T) {
+	Seed(42)
+	bn := BuildAlarmNetwork()
+
+	// A and B are one block: sampled one at a time, any change
+	// of a single fault would contradict the observed parity
+	blocks, singles := bn.gibbsBlocks(BayNodes{bn.nodes["A"], bn.nodes["B"], bn.nodes["Alarm"]})
+	if len(blocks) != 1 || len(blocks[0].free) != 2 || len(blocks[0].computed) != 1 || len(singles) != 0 {
+		t.Errorf("unexpected blocks: %v, singles: %v", blocks, singles)
+	}
+
+	evidence := map[string]string{"Parity": "T", "Sensor": "T"}
+	exp, err := bn.ExactInference(evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	act := bn.GibbsSampling(evidence, 1000, 10000)
+	compareStatMaps(exp, act, t)
+	if act["Alarm"][0] != 1 {
+		t.Errorf("Alarm: Exp 1 != %.4f Act", act["Alarm"][0])
+	}
+}
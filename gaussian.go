@@ -0,0 +1,442 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Continuous nodes with a linear-Gaussian distribution given their
+// continuous parents:
+//
+//	X | parents ~ N(Intercept + sum_i Weights[i] * parent_i, Variance)
+//
+// A continuous node can also have binary parents, with one linear
+// Gaussian per assignment of them: a conditional linear Gaussian (CLG)
+// - the assignment of a continuous node is its value formatted as a
+//   number, e.g. "1.5", see SetValue
+// - the marginal of a continuous node in a StatMap is [mean, variance]
+// - binary nodes cannot have continuous parents
+// - networks of continuous nodes only are solved exactly by
+//   GaussianInference, hybrid networks by HybridInference; the
+//   discrete engines reject continuous nodes
+type LinearGaussian struct {
+	Intercept float64
+	Weights   []float64
+	Variance  float64
+}
+
+// Generate a continuous node with a linear-Gaussian distribution,
+// one weight per parent
+func NewGaussianNode(name string, parents []string, intercept float64, weights []float64, variance float64) *Node {
+	return NewCLGNode(name, parents, map[string]*LinearGaussian{
+		"": {
+			Intercept: intercept,
+			Weights:   weights,
+			Variance:  variance,
+		},
+	})
+}
+
+// Generate a continuous node with a conditional linear-Gaussian
+// distribution: dist is keyed by the states of the binary parents in
+// their order, e.g. "TF", and the weights of every linear Gaussian are
+// those of the continuous parents in their order
+func NewCLGNode(name string, parents []string, dist map[string]*LinearGaussian) *Node {
+	return &Node{
+		name:        name,
+		parentNames: parents,
+		parentIds:   make([]*Node, 0, 4),
+		childIds:    make([]*Node, 0, 4),
+		gaussians:   dist,
+	}
+}
+
+func (lg *LinearGaussian) validate(numParents int) error {
+	if lg == nil {
+		return fmt.Errorf("linear Gaussian is missing")
+	}
+	if len(lg.Weights) != numParents {
+		return fmt.Errorf("linear Gaussian has %d weights for %d parents", len(lg.Weights), numParents)
+	}
+	if !(lg.Variance > 0) || math.IsInf(lg.Variance, 1) {
+		return fmt.Errorf("linear Gaussian variance %f is not positive", lg.Variance)
+	}
+	return finite(append([]float64{lg.Intercept}, lg.Weights...))
+}
+
+// Reports whether the node is continuous
+func (self *Node) IsContinuous() bool {
+	return self.gaussians != nil
+}
+
+// returns the distribution of a continuous node without
+// binary parents, nil for other nodes
+func (self *Node) GetLinearGaussian() *LinearGaussian {
+	return self.gaussians[""]
+}
+
+// returns the distribution of a continuous node for the states of
+// its binary parents, e.g. "TF", nil if there is none
+func (self *Node) GetConditionalLinearGaussian(key string) *LinearGaussian {
+	return self.gaussians[key]
+}
+
+// the value of a continuous node, 0 if it is unassigned
+func (self *Node) Value() float64 {
+	if self.assignment == "" {
+		return 0
+	}
+	return self.value
+}
+
+// assigns the value of a continuous node
+func (self *Node) SetValue(value float64) {
+	self.value = value
+	self.assignment = strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// the linear Gaussian of a continuous node for the
+// assignment of its binary parents
+func (self *Node) linearGaussian() *LinearGaussian {
+	var key []byte
+	for _, parent := range self.parentIds {
+		if !parent.IsContinuous() {
+			key = append(key, parent.assignment...)
+		}
+	}
+	return self.gaussians[string(key)]
+}
+
+// the mean and variance of a continuous node given the
+// assignment of its parents
+func (self *Node) moments() (float64, float64) {
+	lg := self.linearGaussian()
+	m, i := lg.Intercept, 0
+	for _, parent := range self.parentIds {
+		if parent.IsContinuous() {
+			m += lg.Weights[i] * parent.Value()
+			i++
+		}
+	}
+	return m, lg.Variance
+}
+
+// the probability of the assignment of a binary node, or the density
+// of the value of a continuous node, given the assignment of its parents
+func (self *Node) likelihood() float64 {
+	if self.gaussians == nil {
+		return self.SampleOnCondition(self.assignment)
+	}
+	m, v := self.moments()
+	d := self.value - m
+	return math.Exp(-d*d/(2*v)) / math.Sqrt(2*math.Pi*v)
+}
+
+// samples the node given the assignment of its parents
+func (self *Node) draw() {
+	if self.gaussians != nil {
+		m, v := self.moments()
+		self.SetValue(m + rng.NormFloat64()*math.Sqrt(v))
+		return
+	}
+	self.SetAssignment(self.Sample())
+}
+
+// a binary node cannot have continuous parents, as its CPT is keyed
+// on the states of the parents. A continuous node needs a linear
+// Gaussian for every assignment of its binary parents
+func (self *Node) validateParentKinds() error {
+	binary, continuous := 0, 0
+	for _, parent := range self.parentIds {
+		if parent.IsContinuous() {
+			continuous++
+		} else {
+			binary++
+		}
+	}
+	if !self.IsContinuous() {
+		if continuous > 0 {
+			return fmt.Errorf("%s is binary and cannot have continuous parents", self.name)
+		}
+		return nil
+	}
+
+	keys := cptKeys(binary)
+	if len(self.gaussians) != len(keys) {
+		return fmt.Errorf("%s has %d linear Gaussians for %d assignments of its binary parents",
+			self.name, len(self.gaussians), len(keys))
+	}
+	for _, key := range keys {
+		if err := self.gaussians[key].validate(continuous); err != nil {
+			return fmt.Errorf("%s (%s): %v", self.name, key, err)
+		}
+	}
+	return nil
+}
+
+// reports an error if the network has a continuous node
+func (bn *BayesianNetwork) checkDiscrete() error {
+	for _, node := range bn.nodeIndex {
+		if node.IsContinuous() {
+			return fmt.Errorf("%s is continuous, only binary nodes are supported", node.Name())
+		}
+	}
+	return nil
+}
+
+// reports an error if the network has a binary node
+func (bn *BayesianNetwork) checkGaussian() error {
+	for _, node := range bn.nodeIndex {
+		if !node.IsContinuous() {
+			return fmt.Errorf("%s is binary, only continuous nodes are supported", node.Name())
+		}
+	}
+	return nil
+}
+
+// A Gaussian potential in canonical (information) form over vars:
+//
+//	phi(x) = exp(g + h'x - x'Kx/2)
+//
+// products of potentials add their parameters, and observing a
+// variable only needs the rows of K, so the joint density of a
+// network is built without inverting any matrix
+type canonical struct {
+	vars  []string
+	index map[string]int
+	K     [][]float64
+	h     []float64
+	g     float64
+}
+
+func newCanonical(vars []string) *canonical {
+	return &canonical{
+		vars:  vars,
+		index: positions(vars),
+		K:     newMatrix(len(vars), len(vars)),
+		h:     make([]float64, len(vars)),
+	}
+}
+
+// multiplies the potential by the density of the node given its parents.
+// With z = (x, parents) and a = (1, -weights), the exponent is
+//
+//	-(a'z - intercept)^2 / 2 variance - log(2 pi variance) / 2
+func (c *canonical) multiply(name string, parents []string, lg *LinearGaussian) {
+	pos := make([]int, 0, len(parents)+1)
+	a := make([]float64, 0, len(parents)+1)
+	pos = append(pos, c.index[name])
+	a = append(a, 1)
+	for i, parent := range parents {
+		pos = append(pos, c.index[parent])
+		a = append(a, -lg.Weights[i])
+	}
+
+	for i, pi := range pos {
+		for j, pj := range pos {
+			c.K[pi][pj] += a[i] * a[j] / lg.Variance
+		}
+		c.h[pi] += lg.Intercept * a[i] / lg.Variance
+	}
+	c.g -= lg.Intercept*lg.Intercept/(2*lg.Variance) + math.Log(2*math.Pi*lg.Variance)/2
+}
+
+// the potential as a function of the unobserved variables, for the
+// observed values of the others
+func (c *canonical) condition(evidence map[string]float64) *canonical {
+	var vars []string
+	for _, v := range c.vars {
+		if _, ok := evidence[v]; !ok {
+			vars = append(vars, v)
+		}
+	}
+
+	r := newCanonical(vars)
+	r.g = c.g
+	for i, vi := range c.vars {
+		ei, observed := evidence[vi]
+		if !observed {
+			ri := r.index[vi]
+			r.h[ri] += c.h[i]
+			for j, vj := range c.vars {
+				if ej, ok := evidence[vj]; ok {
+					r.h[ri] -= c.K[i][j] * ej
+				} else {
+					r.K[ri][r.index[vj]] = c.K[i][j]
+				}
+			}
+			continue
+		}
+		r.g += c.h[i] * ei
+		for j, vj := range c.vars {
+			if ej, ok := evidence[vj]; ok {
+				r.g -= c.K[i][j] * ei * ej / 2
+			}
+		}
+	}
+	return r
+}
+
+// the normalized potential as a multivariate normal, along with
+// the log of the integral of the potential
+func (c *canonical) normal() (*MultivariateNormal, float64, error) {
+	if len(c.vars) == 0 {
+		return &MultivariateNormal{}, c.g, nil
+	}
+	covariance, logDet, err := invertSPD(c.K)
+	if err != nil {
+		return nil, 0, err
+	}
+	mean := multiply(covariance, c.h)
+
+	logZ := c.g + (float64(len(c.vars))*math.Log(2*math.Pi)-logDet)/2
+	for i := range mean {
+		logZ += c.h[i] * mean[i] / 2
+	}
+	return &MultivariateNormal{Names: c.vars, Mean: mean, Covariance: covariance}, logZ, nil
+}
+
+// the joint density of the continuous nodes in canonical form, for
+// the assignment of the binary parents of continuous nodes
+func (bn *BayesianNetwork) canonical(assignment map[string]string) *canonical {
+	var continuous BayNodes
+	for _, node := range bn.nodeIndex {
+		if node.IsContinuous() {
+			continuous = append(continuous, node)
+		}
+	}
+
+	c := newCanonical(nodeNames(continuous))
+	for _, node := range continuous {
+		var key []byte
+		var parents []string
+		for _, parent := range node.parentIds {
+			if parent.IsContinuous() {
+				parents = append(parents, parent.Name())
+			} else {
+				key = append(key, assignment[parent.Name()]...)
+			}
+		}
+		c.multiply(node.Name(), parents, node.gaussians[string(key)])
+	}
+	return c
+}
+
+// validates continuous evidence against the network
+func (bn *BayesianNetwork) checkContinuousEvidence(evidence map[string]float64) error {
+	for name, value := range evidence {
+		if bn.nodes[name] == nil {
+			return fmt.Errorf("Node '%s' does not exist in network", name)
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("%s: value %f is not finite", name, value)
+		}
+	}
+	return nil
+}
+
+// Computes the joint posterior of the unobserved nodes of a network of
+// continuous nodes given the observed values, as a multivariate normal
+func (bn *BayesianNetwork) PosteriorNormal(evidence map[string]float64) (*MultivariateNormal, error) {
+	if err := bn.checkGaussian(); err != nil {
+		return nil, err
+	}
+	if err := bn.checkContinuousEvidence(evidence); err != nil {
+		return nil, err
+	}
+	mvn, _, err := bn.canonical(nil).condition(evidence).normal()
+	return mvn, err
+}
+
+// Computes the exact posterior marginal [mean, variance] of every node
+// of a network of continuous nodes given the observed values
+// - observed nodes get [value, 0]
+func (bn *BayesianNetwork) GaussianInference(evidence map[string]float64) (StatMap, error) {
+	mvn, err := bn.PosteriorNormal(evidence)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(StatMap, len(bn.nodeIndex))
+	for name, value := range evidence {
+		stats[name] = []float64{value, 0}
+	}
+	for i, name := range mvn.Names {
+		stats[name] = []float64{mvn.Mean[i], mvn.Covariance[i][i]}
+	}
+	return stats, nil
+}
+
+// Computes the natural logarithm of the density of the observed values
+// in a network of continuous nodes
+func (bn *BayesianNetwork) LogEvidenceDensity(evidence map[string]float64) (float64, error) {
+	if err := bn.checkGaussian(); err != nil {
+		return 0, err
+	}
+	if err := bn.checkContinuousEvidence(evidence); err != nil {
+		return 0, err
+	}
+	_, logZ, err := bn.canonical(nil).condition(evidence).normal()
+	return logZ, err
+}
+
+// A multivariate normal distribution over the named variables
+type MultivariateNormal struct {
+	Names      []string
+	Mean       []float64
+	Covariance [][]float64
+}
+
+// Returns the joint distribution of a network of continuous nodes
+func (bn *BayesianNetwork) Normal() (*MultivariateNormal, error) {
+	return bn.PosteriorNormal(nil)
+}
+
+// weights below the threshold are left out as edges by NetworkFromNormal
+const gaussianEdgeThreshold = 1e-12
+
+// Generate a network of continuous nodes with the joint distribution,
+// by the chain rule in the order of the names: the node of a variable
+// is its regression on the variables before it, and the variables with
+// a weight of 0 are left out as parents
+func NetworkFromNormal(mvn *MultivariateNormal) (*BayesianNetwork, error) {
+	n := len(mvn.Names)
+	if len(mvn.Mean) != n || len(mvn.Covariance) != n {
+		return nil, fmt.Errorf("normal over %d variables has %d means and %d covariance rows",
+			n, len(mvn.Mean), len(mvn.Covariance))
+	}
+
+	nodes := make(BayNodes, 0, n)
+	for i, name := range mvn.Names {
+		if len(mvn.Covariance[i]) != n {
+			return nil, fmt.Errorf("covariance row %d has %d columns, expected %d", i, len(mvn.Covariance[i]), n)
+		}
+
+		// weights = Cov(before)^-1 Cov(before, x)
+		before := newMatrix(i, i)
+		cross := make([]float64, i)
+		for j := 0; j < i; j++ {
+			copy(before[j], mvn.Covariance[j][:i])
+			cross[j] = mvn.Covariance[j][i]
+		}
+		weights, err := solveLinear(before, cross)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+
+		intercept, variance := mvn.Mean[i], mvn.Covariance[i][i]
+		var parents []string
+		var kept []float64
+		for j, w := range weights {
+			intercept -= w * mvn.Mean[j]
+			variance -= w * cross[j]
+			if math.Abs(w) > gaussianEdgeThreshold {
+				parents = append(parents, mvn.Names[j])
+				kept = append(kept, w)
+			}
+		}
+		nodes = append(nodes, NewGaussianNode(name, parents, intercept, kept, variance))
+	}
+	return buildBayesianNetwork(nodes...)
+}
@@ -0,0 +1,154 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+// X ~ N(1, 4), Y = 2 + X/2 + N(0, 1), Z = Y - X - 1 + N(0, 0.5)
+func BuildGaussianNetwork() *BayesianNetwork {
+	return NewBayesianNetwork(
+		NewGaussianNode("X", nil, 1, nil, 4),
+		NewGaussianNode("Y", []string{"X"}, 2, []float64{0.5}, 1),
+		NewGaussianNode("Z", []string{"Y", "X"}, -1, []float64{1, -1}, 0.5),
+	)
+}
+
+func expectNormal(t *testing.T, exp, act *MultivariateNormal) {
+	for i := range exp.Names {
+		if math.Abs(exp.Mean[i]-act.Mean[i]) > 1e-9 {
+			t.Errorf("mean of %s: Exp %.6f != %.6f Act", exp.Names[i], exp.Mean[i], act.Mean[i])
+		}
+		for j := range exp.Names {
+			if math.Abs(exp.Covariance[i][j]-act.Covariance[i][j]) > 1e-9 {
+				t.Errorf("Cov(%s, %s): Exp %.6f != %.6f Act", exp.Names[i], exp.Names[j],
+					exp.Covariance[i][j], act.Covariance[i][j])
+			}
+		}
+	}
+}
+
+func TestGaussianNormal(t *testing.T) {
+	bn := BuildGaussianNetwork()
+	mvn, err := bn.Normal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNormal(t, &MultivariateNormal{
+		Names: []string{"X", "Y", "Z"},
+		Mean:  []float64{1, 2.5, 0.5},
+		Covariance: [][]float64{
+			{4, 2, -2},
+			{2, 2, 0},
+			{-2, 0, 2.5},
+		},
+	}, mvn)
+
+	// back to a network by the chain rule in the same order
+	rebuilt, err := NetworkFromNormal(mvn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := rebuilt.GetNode("Z").GetLinearGaussian()
+	if len(z.Weights) != 2 || math.Abs(z.Weights[0]+1) > 1e-9 || math.Abs(z.Variance-0.5) > 1e-9 {
+		t.Errorf("Z: Exp weights [-1 1], variance 0.5 != %+v Act", *z)
+	}
+	again, err := rebuilt.Normal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNormal(t, mvn, again)
+
+	// independent variables have no edge
+	independent, err := NetworkFromNormal(&MultivariateNormal{
+		Names:      []string{"A", "B"},
+		Mean:       []float64{0, 0},
+		Covariance: [][]float64{{1, 0}, {0, 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := independent.GetNode("B").NumParents(); n != 0 {
+		t.Errorf("Exp 0 != %d Act parents of B", n)
+	}
+}
+
+func TestGaussianInference(t *testing.T) {
+	bn := BuildGaussianNetwork()
+
+	// conditioning the joint normal on Y = 3:
+	// mean_u + Cov(u, Y) / Var(Y) * (3 - 2.5)
+	stats, err := bn.GaussianInference(map[string]float64{"Y": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, exp := range map[string][]float64{
+		"X": {1 + 2.0/2*0.5, 4 - 2*2.0/2},
+		"Y": {3, 0},
+		"Z": {0.5, 2.5},
+	} {
+		if math.Abs(stats[name][0]-exp[0]) > 1e-9 || math.Abs(stats[name][1]-exp[1]) > 1e-9 {
+			t.Errorf("%s: Exp %v != %v Act", name, exp, stats[name])
+		}
+	}
+
+	// Y ~ N(2.5, 2)
+	logP, err := bn.LogEvidenceDensity(map[string]float64{"Y": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := -0.25/(2*2) - math.Log(2*math.Pi*2)/2; math.Abs(logP-exp) > 1e-9 {
+		t.Errorf("log p(Y=3): Exp %.6f != %.6f Act", exp, logP)
+	}
+	// the density of everything is the product of the nodes
+	values := map[string]float64{"X": 0, "Y": 2, "Z": 1}
+	logP, err = bn.LogEvidenceDensity(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := normalLogDensity(0, 1, 4) + normalLogDensity(2, 2, 1) + normalLogDensity(1, 1, 0.5)
+	if math.Abs(logP-exp) > 1e-9 {
+		t.Errorf("log p(X, Y, Z): Exp %.6f != %.6f Act", exp, logP)
+	}
+
+	if _, err := bn.ExactInference(nil); err == nil {
+		t.Errorf("Expected an error for the discrete engine")
+	}
+	if _, err := bn.GaussianInference(map[string]float64{"W": 1}); err == nil {
+		t.Errorf("Expected an error for an unknown node")
+	}
+	if _, err := buildBayesianNetwork(
+		NewGaussianNode("X", nil, 0, nil, 1),
+		NewNode("B", []string{"X"}, map[string]float64{"T": 0.5, "F": 0.5}),
+	); err == nil {
+		t.Errorf("Expected an error for a binary node with a continuous parent")
+	}
+}
+
+func normalLogDensity(x, mean, variance float64) float64 {
+	return -(x-mean)*(x-mean)/(2*variance) - math.Log(2*math.Pi*variance)/2
+}
+
+func TestGaussianAncestralSampling(t *testing.T) {
+	Seed(42)
+	bn := BuildGaussianNetwork()
+	stats := bn.AncestralSampling(20000)
+	exp, err := bn.GaussianInference(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, dist := range exp {
+		if math.Abs(stats[name][0]-dist[0]) > 0.1 || math.Abs(stats[name][1]-dist[1])/dist[1] > 0.1 {
+			t.Errorf("%s: Exp %v !≈ %v Act", name, dist, stats[name])
+		}
+	}
+}
+
+func TestGaussianSampleDataset(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic for a dataset of continuous nodes")
+		}
+	}()
+	BuildGaussianNetwork().SampleDataset(1)
+}
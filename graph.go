@@ -0,0 +1,162 @@
+package BayesianNetwork
+
+import (
+	"sort"
+)
+
+// Structural queries on the DAG.
+// - every query returns its nodes sorted by topological order
+//   (the index of the network)
+// - panics if a name does not exist in the network
+
+// every node with a directed path to the node
+func (bn *BayesianNetwork) Ancestors(name string) BayNodes {
+	return sorted(bn.ancestors(bn.mustGetNodes([]string{name})[0]))
+}
+
+// every node with a directed path from the node
+func (bn *BayesianNetwork) Descendants(name string) BayNodes {
+	return sorted(bn.descendants(bn.mustGetNodes([]string{name})[0]))
+}
+
+// the parents, the children and the other parents of the children
+// of the node. Given its Markov blanket, a node is independent of
+// every other node in the network
+func (bn *BayesianNetwork) MarkovBlanket(name string) BayNodes {
+	node := bn.mustGetNodes([]string{name})[0]
+
+	seen := map[*Node]bool{node: true}
+	blanket := make(BayNodes, 0, node.NumParents()+node.NumChildren())
+	add := func(nodes BayNodes) {
+		for _, n := range nodes {
+			if !seen[n] {
+				seen[n] = true
+				blanket = append(blanket, n)
+			}
+		}
+	}
+
+	add(node.GetParents())
+	for _, child := range node.GetChildren() {
+		add(BayNodes{child})
+		add(child.GetParents())
+	}
+	return sorted(blanket)
+}
+
+// The moral graph: the undirected graph where every node is
+// connected to its parents, its children and the other parents
+// of its children. Maps every node onto its neighbours
+func (bn *BayesianNetwork) MoralGraph() map[string]BayNodes {
+	graph := make(map[string]BayNodes, len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		// in the moral graph, the neighbours of a node are its Markov blanket
+		graph[node.Name()] = bn.MarkovBlanket(node.Name())
+	}
+	return graph
+}
+
+// every node without parents
+func (bn *BayesianNetwork) Roots() BayNodes {
+	roots := make(BayNodes, 0, 5)
+	for _, node := range bn.nodeIndex {
+		if node.IsRoot() {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// every node without children
+func (bn *BayesianNetwork) Leaves() BayNodes {
+	leaves := make(BayNodes, 0, 5)
+	for _, node := range bn.nodeIndex {
+		if node.NumChildren() == 0 {
+			leaves = append(leaves, node)
+		}
+	}
+	return leaves
+}
+
+// the number of edges on the longest directed path from
+// a root to the node. Roots have depth 0
+func (bn *BayesianNetwork) Depth(name string) int {
+	return bn.depths()[bn.mustGetNodes([]string{name})[0]]
+}
+
+// the longest directed path in the network, from a root to a leaf
+func (bn *BayesianNetwork) LongestPath() BayNodes {
+	if len(bn.nodeIndex) == 0 {
+		return BayNodes{}
+	}
+
+	depth := bn.depths()
+	deepest := bn.nodeIndex[0]
+	for _, node := range bn.nodeIndex {
+		if depth[node] > depth[deepest] {
+			deepest = node
+		}
+	}
+
+	// walk back through the parents that realize the depth
+	path := BayNodes{deepest}
+	for node := deepest; !node.IsRoot(); {
+		for _, parent := range node.GetParents() {
+			if depth[parent] == depth[node]-1 {
+				node = parent
+				break
+			}
+		}
+		path = append(path, node)
+	}
+	return sorted(path)
+}
+
+// depth of every node, computed in index order
+func (bn *BayesianNetwork) depths() map[*Node]int {
+	depth := make(map[*Node]int, len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		for _, parent := range node.GetParents() {
+			if depth[parent]+1 > depth[node] {
+				depth[node] = depth[parent] + 1
+			}
+		}
+	}
+	return depth
+}
+
+// every node reachable by following the parent links
+func (bn *BayesianNetwork) ancestors(node *Node) BayNodes {
+	return traverse(node, (*Node).GetParents)
+}
+
+// every node reachable by following the child links
+func (bn *BayesianNetwork) descendants(node *Node) BayNodes {
+	return traverse(node, (*Node).GetChildren)
+}
+
+func traverse(node *Node, next func(*Node) BayNodes) BayNodes {
+	seen := map[*Node]bool{node: true}
+	var result BayNodes
+	queue := append(BayNodes{}, next(node)...)
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		result = append(result, n)
+		queue = append(queue, next(n)...)
+	}
+	return result
+}
+
+// sorts the nodes on their id, i.e. topologically
+func sorted(nodes BayNodes) BayNodes {
+	if nodes == nil {
+		nodes = BayNodes{}
+	}
+	sort.Sort(nodes)
+	return nodes
+}
@@ -0,0 +1,71 @@
+package BayesianNetwork
+
+import (
+	"testing"
+)
+
+func names(nodes BayNodes) []string {
+	n := make([]string, len(nodes))
+	for i, node := range nodes {
+		n[i] = node.Name()
+	}
+	return n
+}
+
+func expectNames(t *testing.T, what string, nodes BayNodes, exp ...string) {
+	act := names(nodes)
+	if len(act) != len(exp) {
+		t.Errorf("%s: Exp %v != %v Act", what, exp, act)
+		return
+	}
+	for i := range exp {
+		if act[i] != exp[i] {
+			t.Errorf("%s: Exp %v != %v Act", what, exp, act)
+			return
+		}
+	}
+}
+
+func TestGraphQueries(t *testing.T) {
+	bn := BuildStudentNetwork()
+	bn.ValidateIndex(t)
+
+	expectNames(t, "Ancestors(U)", bn.Ancestors("U"), "E", "I", "D", "P", "R")
+	expectNames(t, "Descendants(I)", bn.Descendants("I"), "P", "R", "J", "U")
+	expectNames(t, "MarkovBlanket(P)", bn.MarkovBlanket("P"), "E", "I", "D", "R", "J", "U")
+	expectNames(t, "MarkovBlanket(E)", bn.MarkovBlanket("E"), "I", "D", "P")
+	expectNames(t, "Roots", bn.Roots(), "E", "I", "D")
+	expectNames(t, "Leaves", bn.Leaves(), "J", "U")
+	expectNames(t, "MoralGraph[R]", bn.MoralGraph()["R"], "I", "D", "P", "U")
+
+	if d := bn.Depth("U"); d != 2 {
+		t.Errorf("Depth(U): Exp 2 != %d Act", d)
+	}
+	if path := bn.LongestPath(); len(path) != 3 {
+		t.Errorf("LongestPath: %v", path)
+	}
+}
+
+// a child listed before the parents that are deeper in the graph
+// must still be indexed after them
+func TestTopologicalIndex(t *testing.T) {
+	a := NewRootNode("A", 0.5)
+	b := NewNode("B", []string{"A"}, map[string]float64{"T": 0.9, "F": 0.1})
+	c := NewNode("C", []string{"B"}, map[string]float64{"T": 0.9, "F": 0.1})
+	x := NewNode("X", []string{"A", "C"}, map[string]float64{"TT": 0.9, "TF": 0.5, "FT": 0.5, "FF": 0.1})
+
+	bn := NewBayesianNetwork(x, a, b, c)
+	bn.ValidateIndex(t)
+	expectNames(t, "index", bn.GetNodes(), "A", "B", "C", "X")
+
+	// sampling follows the index, so it must not panic
+	bn.AncestralSampling(10)
+
+	cyclic := []*Node{
+		NewNode("P", []string{"Q"}, map[string]float64{"T": 0.5, "F": 0.5}),
+		NewNode("Q", []string{"P"}, map[string]float64{"T": 0.5, "F": 0.5}),
+	}
+	if _, err := buildBayesianNetwork(cyclic...); err == nil {
+		t.Error("expected an error for a cyclic network")
+	}
+}
@@ -0,0 +1,450 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+)
+
+// A Hidden Markov Model over a binary hidden state and any number
+// of binary observation variables, which are independent given the
+// state. The model is held as three kinds of Nodes:
+// - the initial distribution, a root named after the state
+// - the transition, the state with the single parent Previous(state)
+// - one emission per observation variable, with the state as parent
+// A sequence holds one evidence map over the observation variables
+// per step, missing observations are left out of the map.
+// Every computation is done in log space, so sequences of any
+// length can be used
+type HMM struct {
+	initial    *Node
+	transition *Node
+	emissions  BayNodes
+}
+
+// Creates a HMM from the initial, transition and emission nodes,
+// see above
+// - the HMM keeps copies of the nodes, so BaumWelch does not
+//   change the CPTs of the given nodes
+// - panics if the nodes do not form a HMM
+func NewHMM(initial, transition *Node, emissions ...*Node) *HMM {
+	copies := make(BayNodes, len(emissions))
+	for i, emission := range emissions {
+		copies[i] = emission.clone()
+	}
+	h, err := buildHMM(initial.clone(), transition.clone(), copies)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+func buildHMM(initial, transition *Node, emissions BayNodes) (*HMM, error) {
+	state := initial.Name()
+	for _, node := range append(BayNodes{initial, transition}, emissions...) {
+		if node.cpd != nil {
+			return nil, fmt.Errorf("HMM node '%s' must have a CPT", node.Name())
+		}
+	}
+	if len(initial.parentNames) != 0 {
+		return nil, fmt.Errorf("initial node '%s' must be a root", state)
+	}
+	if err := initial.validateCPT(0); err != nil {
+		return nil, err
+	}
+
+	if transition.Name() != state || len(transition.parentNames) != 1 || transition.parentNames[0] != Previous(state) {
+		return nil, fmt.Errorf("transition node '%s' must be '%s' with the single parent '%s'",
+			transition.Name(), state, Previous(state))
+	}
+	if err := transition.validateCPT(1); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{state: true}
+	for _, emission := range emissions {
+		if seen[emission.Name()] {
+			return nil, fmt.Errorf("Duplicate nodeName: %s", emission.Name())
+		}
+		seen[emission.Name()] = true
+		if len(emission.parentNames) != 1 || emission.parentNames[0] != state {
+			return nil, fmt.Errorf("emission node '%s' must have the single parent '%s'", emission.Name(), state)
+		}
+		if err := emission.validateCPT(1); err != nil {
+			return nil, err
+		}
+	}
+
+	return &HMM{
+		initial:    initial,
+		transition: transition,
+		emissions:  emissions,
+	}, nil
+}
+
+// Converts a dynamic network with the structure of a HMM: a single
+// variable with an edge between the slices, the state, whose only
+// parent is its own previous slice, and variables with the state as
+// their only parent and the same CPT in both slices
+func HMMFromDynamic(dbn *DynamicBayesianNetwork) (*HMM, error) {
+	var initial, transition *Node
+	for _, name := range dbn.frontier {
+		if initial != nil {
+			return nil, fmt.Errorf("a HMM has a single state, both '%s' and '%s' reach the next slice", initial.Name(), name)
+		}
+		initial = dbn.prior.nodes[name].clone()
+		transition = dbn.transition.nodes[name].clone()
+	}
+	if initial == nil {
+		return nil, fmt.Errorf("no variable reaches the next slice")
+	}
+
+	emissions := make(BayNodes, 0, len(dbn.variables)-1)
+	for _, name := range dbn.variables {
+		if name == initial.Name() {
+			continue
+		}
+		prior, next := dbn.prior.nodes[name], dbn.transition.nodes[name]
+		for _, node := range []*Node{prior, next} {
+			if len(node.parentNames) != 1 || node.parentNames[0] != initial.Name() {
+				return nil, fmt.Errorf("emission node '%s' must have the single parent '%s'", name, initial.Name())
+			}
+		}
+		for _, key := range []string{"T", "F"} {
+			if prior.cpt[key] != next.cpt[key] {
+				return nil, fmt.Errorf("emission node '%s' differs between the slices", name)
+			}
+		}
+		emissions = append(emissions, next.clone())
+	}
+
+	return buildHMM(initial, transition, emissions)
+}
+
+// Converts the HMM into a 2-slice network
+func (h *HMM) Dynamic() *DynamicBayesianNetwork {
+	prior := BayNodes{h.initial.clone()}
+	transition := BayNodes{h.transition.clone()}
+	for _, emission := range h.emissions {
+		prior = append(prior, emission.clone())
+		transition = append(transition, emission.clone())
+	}
+	return NewDynamicBayesianNetwork(prior, transition)
+}
+
+// name of the hidden state variable
+func (h *HMM) State() string {
+	return h.initial.Name()
+}
+
+// names of the observation variables
+func (h *HMM) Observations() []string {
+	return nodeNames(h.emissions)
+}
+
+// The log-likelihood of the sequence
+// - returns math.Inf(-1) if the sequence is impossible
+func (h *HMM) LogLikelihood(sequence []map[string]string) (float64, error) {
+	logB, err := h.logEmissions(sequence)
+	if err != nil {
+		return 0, err
+	}
+	if len(logB) == 0 {
+		return 0, nil
+	}
+	alpha := h.forward(logB)
+	return logSumExp(alpha[len(alpha)-1][:]...), nil
+}
+
+// Filtering: [P(S_t=T | o_0..t), P(S_t=F | o_0..t)] of every step
+func (h *HMM) Filter(sequence []map[string]string) ([][]float64, error) {
+	logB, err := h.logEmissions(sequence)
+	if err != nil {
+		return nil, err
+	}
+
+	alpha := h.forward(logB)
+	filtered := make([][]float64, len(alpha))
+	for t, a := range alpha {
+		z := logSumExp(a[:]...)
+		if math.IsInf(z, -1) {
+			return nil, fmt.Errorf("step %d: sequence has zero probability", t)
+		}
+		filtered[t] = []float64{math.Exp(a[0] - z), math.Exp(a[1] - z)}
+	}
+	return filtered, nil
+}
+
+// Smoothing by the forward-backward algorithm: the posterior
+// [P(S_t=T | o), P(S_t=F | o)] of every step, along with the
+// log-likelihood of the sequence
+func (h *HMM) ForwardBackward(sequence []map[string]string) ([][]float64, float64, error) {
+	logB, err := h.logEmissions(sequence)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(logB) == 0 {
+		return nil, 0, nil
+	}
+
+	gamma, ll := h.posteriors(logB, h.forward(logB), h.backward(logB))
+	if math.IsInf(ll, -1) {
+		return nil, 0, fmt.Errorf("sequence has zero probability")
+	}
+	return gamma, ll, nil
+}
+
+// Viterbi decoding: the most likely state sequence given the
+// observations, along with the log of its joint probability
+// with the observations
+func (h *HMM) Viterbi(sequence []map[string]string) ([]string, float64, error) {
+	logB, err := h.logEmissions(sequence)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(logB) == 0 {
+		return nil, 0, nil
+	}
+
+	logPi, logA := h.logInitial(), h.logTransition()
+
+	// delta[t][s] is the log probability of the best path ending
+	// in s at step t, back[t][s] the state before it
+	delta := make([][2]float64, len(logB))
+	back := make([][2]int, len(logB))
+	for s := 0; s < 2; s++ {
+		delta[0][s] = logPi[s] + logB[0][s]
+	}
+	for t := 1; t < len(logB); t++ {
+		for s := 0; s < 2; s++ {
+			best := 0
+			if delta[t-1][1]+logA[1][s] > delta[t-1][0]+logA[0][s] {
+				best = 1
+			}
+			delta[t][s] = delta[t-1][best] + logA[best][s] + logB[t][s]
+			back[t][s] = best
+		}
+	}
+
+	last := len(logB) - 1
+	s := 0
+	if delta[last][1] > delta[last][0] {
+		s = 1
+	}
+	logP := delta[last][s]
+	if math.IsInf(logP, -1) {
+		return nil, 0, fmt.Errorf("sequence has zero probability")
+	}
+
+	path := make([]string, len(logB))
+	for t := last; t >= 0; t-- {
+		path[t] = bitState(s)
+		s = back[t][s]
+	}
+	return path, logP, nil
+}
+
+// Baum-Welch training: fits the CPTs of the HMM to the sequences
+// by expectation maximization, until the log-likelihood improves by
+// less than tolerance or after the given number of iterations.
+// Returns the log-likelihood of the sequences under the final model
+// - parameters without expected counts keep their value
+func (h *HMM) BaumWelch(sequences [][]map[string]string, iterations int, tolerance float64) (float64, error) {
+	logBs := make([][][2]float64, len(sequences))
+	for i, sequence := range sequences {
+		logB, err := h.logEmissions(sequence)
+		if err != nil {
+			return 0, fmt.Errorf("sequence %d: %v", i, err)
+		}
+		logBs[i] = logB
+	}
+
+	previous := math.Inf(-1)
+	for iteration := 0; ; iteration++ {
+		// E-step: expected counts under the current model
+		var initial, transitions, transitionVisits [2]float64
+		started := 0.0
+		emissions := make([][2]float64, len(h.emissions))
+		emissionVisits := make([][2]float64, len(h.emissions))
+
+		logA := h.logTransition()
+		ll := 0.0
+		for i, logB := range logBs {
+			if len(logB) == 0 {
+				continue
+			}
+			alpha, beta := h.forward(logB), h.backward(logB)
+			gamma, seqLL := h.posteriors(logB, alpha, beta)
+			if math.IsInf(seqLL, -1) {
+				return 0, fmt.Errorf("sequence %d has zero probability", i)
+			}
+			ll += seqLL
+
+			for s := 0; s < 2; s++ {
+				initial[s] += gamma[0][s]
+			}
+			for t := range logB {
+				for j, emission := range h.emissions {
+					value, ok := sequences[i][t][emission.Name()]
+					if !ok {
+						continue
+					}
+					for s := 0; s < 2; s++ {
+						emissionVisits[j][s] += gamma[t][s]
+						if value == "T" {
+							emissions[j][s] += gamma[t][s]
+						}
+					}
+				}
+				if t == 0 {
+					continue
+				}
+				for r := 0; r < 2; r++ {
+					transitionVisits[r] += gamma[t-1][r]
+					// xi(r, T) = P(S_t-1=r, S_t=T | o)
+					transitions[r] += math.Exp(alpha[t-1][r] + logA[r][0] + logB[t][0] + beta[t][0] - seqLL)
+				}
+			}
+			started++
+		}
+
+		if iteration == iterations || ll-previous < tolerance {
+			return ll, nil
+		}
+		previous = ll
+
+		// M-step: maximum likelihood estimates from the counts
+		if started > 0 {
+			p := initial[0] / started
+			h.initial.cpt["T"], h.initial.cpt["F"] = p, 1-p
+		}
+		for r, key := range []string{"T", "F"} {
+			if transitionVisits[r] > 0 {
+				h.transition.cpt[key] = transitions[r] / transitionVisits[r]
+			}
+		}
+		for j, emission := range h.emissions {
+			for s, key := range []string{"T", "F"} {
+				if emissionVisits[j][s] > 0 {
+					emission.cpt[key] = emissions[j][s] / emissionVisits[j][s]
+				}
+			}
+		}
+	}
+}
+
+// log P(S_0 = s), indexed by the bit of s
+func (h *HMM) logInitial() [2]float64 {
+	p := h.initial.cpt["T"]
+	return [2]float64{math.Log(p), math.Log(1 - p)}
+}
+
+// log P(S_t = s | S_t-1 = r) at [r][s]
+func (h *HMM) logTransition() [2][2]float64 {
+	var logA [2][2]float64
+	for r, key := range []string{"T", "F"} {
+		p := h.transition.cpt[key]
+		logA[r] = [2]float64{math.Log(p), math.Log(1 - p)}
+	}
+	return logA
+}
+
+// log P(o_t | S_t = s) of every step, indexed by the bit of s
+func (h *HMM) logEmissions(sequence []map[string]string) ([][2]float64, error) {
+	isEmission := make(map[string]*Node, len(h.emissions))
+	for _, emission := range h.emissions {
+		isEmission[emission.Name()] = emission
+	}
+
+	logB := make([][2]float64, len(sequence))
+	for t, observations := range sequence {
+		for name, value := range observations {
+			emission := isEmission[name]
+			if emission == nil {
+				return nil, fmt.Errorf("step %d: '%s' is not an observation variable", t, name)
+			}
+			bit, err := stateBit(value)
+			if err != nil {
+				return nil, fmt.Errorf("step %d: %s: %v", t, name, err)
+			}
+			for s, key := range []string{"T", "F"} {
+				p := emission.cpt[key]
+				if bit == 1 {
+					p = 1 - p
+				}
+				logB[t][s] += math.Log(p)
+			}
+		}
+	}
+	return logB, nil
+}
+
+// log alpha[t][s] = log P(o_0..t, S_t = s)
+func (h *HMM) forward(logB [][2]float64) [][2]float64 {
+	alpha := make([][2]float64, len(logB))
+	if len(logB) == 0 {
+		return alpha
+	}
+
+	logPi, logA := h.logInitial(), h.logTransition()
+	for s := 0; s < 2; s++ {
+		alpha[0][s] = logPi[s] + logB[0][s]
+	}
+	for t := 1; t < len(logB); t++ {
+		for s := 0; s < 2; s++ {
+			alpha[t][s] = logSumExp(alpha[t-1][0]+logA[0][s], alpha[t-1][1]+logA[1][s]) + logB[t][s]
+		}
+	}
+	return alpha
+}
+
+// log beta[t][s] = log P(o_t+1..T | S_t = s)
+func (h *HMM) backward(logB [][2]float64) [][2]float64 {
+	beta := make([][2]float64, len(logB))
+	logA := h.logTransition()
+	for t := len(logB) - 2; t >= 0; t-- {
+		for r := 0; r < 2; r++ {
+			beta[t][r] = logSumExp(
+				logA[r][0]+logB[t+1][0]+beta[t+1][0],
+				logA[r][1]+logB[t+1][1]+beta[t+1][1])
+		}
+	}
+	return beta
+}
+
+// the smoothed posteriors of every step and the log-likelihood
+func (h *HMM) posteriors(logB, alpha, beta [][2]float64) ([][]float64, float64) {
+	last := len(logB) - 1
+	ll := logSumExp(alpha[last][:]...)
+	if math.IsInf(ll, -1) {
+		return nil, ll
+	}
+
+	gamma := make([][]float64, len(logB))
+	for t := range logB {
+		gamma[t] = []float64{
+			math.Exp(alpha[t][0] + beta[t][0] - ll),
+			math.Exp(alpha[t][1] + beta[t][1] - ll),
+		}
+	}
+	return gamma, ll
+}
+
+// log(sum(exp(x))) without overflow
+// - returns math.Inf(-1) if every x is math.Inf(-1)
+func logSumExp(xs ...float64) float64 {
+	max := math.Inf(-1)
+	for _, x := range xs {
+		if x > max {
+			max = x
+		}
+	}
+	if math.IsInf(max, -1) {
+		return max
+	}
+
+	sum := 0.0
+	for _, x := range xs {
+		sum += math.Exp(x - max)
+	}
+	return max + math.Log(sum)
+}
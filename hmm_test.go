@@ -0,0 +1,218 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// the umbrella world as a HMM, with a second observation
+func BuildUmbrellaHMM() *HMM {
+	return NewHMM(
+		NewRootNode("Rain", 0.5),
+		NewNode("Rain", []string{Previous("Rain")}, map[string]float64{"T": 0.7, "F": 0.3}),
+		NewNode("Umbrella", []string{"Rain"}, map[string]float64{"T": 0.9, "F": 0.2}),
+		NewNode("Wet", []string{"Rain"}, map[string]float64{"T": 0.6, "F": 0.1}),
+	)
+}
+
+var umbrellaSequence = []map[string]string{
+	{"Umbrella": "T"},
+	{"Umbrella": "T", "Wet": "F"},
+	{"Umbrella": "F"},
+	{},
+	{"Umbrella": "T", "Wet": "T"},
+	{"Umbrella": "F", "Wet": "F"},
+}
+
+func TestHMMMatchesDynamicNetwork(t *testing.T) {
+	h := BuildUmbrellaHMM()
+	dbn := h.Dynamic()
+
+	filtered, err := h.Filter(umbrellaSequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smoothed, ll, err := h.ForwardBackward(umbrellaSequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expFiltered, err := dbn.Filter(umbrellaSequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expSmoothed, err := dbn.Smooth(umbrellaSequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for s := range umbrellaSequence {
+		if math.Abs(filtered[s][0]-expFiltered[s]["Rain"][0]) > 1e-9 {
+			t.Errorf("filter step %d: Exp %.6f != %.6f Act", s, expFiltered[s]["Rain"][0], filtered[s][0])
+		}
+		if math.Abs(smoothed[s][0]-expSmoothed[s]["Rain"][0]) > 1e-9 {
+			t.Errorf("smooth step %d: Exp %.6f != %.6f Act", s, expSmoothed[s]["Rain"][0], smoothed[s][0])
+		}
+	}
+
+	// the likelihood and the Viterbi path against the unrolled network
+	unrolled := dbn.Unroll(len(umbrellaSequence))
+	evidence := make(map[string]string)
+	for s, observations := range umbrellaSequence {
+		for name, value := range observations {
+			evidence[SliceName(name, s)] = value
+		}
+	}
+	expLL, err := unrolled.LogEvidenceProbability(evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(ll-expLL) > 1e-9 {
+		t.Errorf("log-likelihood: Exp %.6f != %.6f Act", expLL, ll)
+	}
+
+	// MPE also maximizes over the missing observations, Viterbi sums
+	// them out, so both only agree on fully observed sequences
+	observed := []map[string]string{
+		{"Umbrella": "T", "Wet": "T"},
+		{"Umbrella": "T", "Wet": "F"},
+		{"Umbrella": "F", "Wet": "F"},
+		{"Umbrella": "T", "Wet": "F"},
+		{"Umbrella": "F", "Wet": "T"},
+		{"Umbrella": "F", "Wet": "F"},
+	}
+	for s, observations := range observed {
+		for name, value := range observations {
+			evidence[SliceName(name, s)] = value
+		}
+	}
+
+	path, logP, err := h.Viterbi(observed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mpe, expLogP, err := unrolled.MPE(evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(logP-expLogP) > 1e-9 {
+		t.Errorf("Viterbi: Exp %.6f != %.6f Act", expLogP, logP)
+	}
+	for s, state := range path {
+		if mpe[SliceName("Rain", s)] != state {
+			t.Errorf("Viterbi step %d: Exp %s != %s Act", s, mpe[SliceName("Rain", s)], state)
+		}
+	}
+}
+
+func TestHMMConversion(t *testing.T) {
+	h, err := HMMFromDynamic(BuildUmbrellaHMM().Dynamic())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.State() != "Rain" || len(h.Observations()) != 2 || h.transition.cpt["F"] != 0.3 {
+		t.Errorf("round trip changed the model: %s %v %v", h.State(), h.Observations(), h.transition.cpt)
+	}
+
+	if _, err := HMMFromDynamic(BuildMachineNetwork()); err == nil {
+		t.Errorf("expected an error for a network with two state variables")
+	}
+}
+
+// long sequences must not underflow
+func TestHMMLongSequence(t *testing.T) {
+	h := BuildUmbrellaHMM()
+	sequence := make([]map[string]string, 10000)
+	for i := range sequence {
+		sequence[i] = umbrellaSequence[i%len(umbrellaSequence)]
+	}
+
+	ll, err := h.LogLikelihood(sequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.IsInf(ll, 0) || math.IsNaN(ll) || ll > -1000 {
+		t.Fatalf("log-likelihood %f", ll)
+	}
+	smoothed, _, err := h.ForwardBackward(sequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := smoothed[5000][0]; math.IsNaN(p) || p <= 0 || p >= 1 {
+		t.Errorf("posterior at step 5000: %f", p)
+	}
+}
+
+func TestBaumWelch(t *testing.T) {
+	truth := BuildUmbrellaHMM()
+
+	// sample sequences from the true model
+	r := rand.New(rand.NewSource(42))
+	sequences := make([][]map[string]string, 20)
+	for i := range sequences {
+		state := r.Float64() < truth.initial.cpt["T"]
+		for s := 0; s < 50; s++ {
+			if s > 0 {
+				state = r.Float64() < truth.transition.cpt[bitState(boolBit(state))]
+			}
+			observations := make(map[string]string)
+			for _, emission := range truth.emissions {
+				p := emission.cpt[bitState(boolBit(state))]
+				observations[emission.Name()] = bitState(boolBit(r.Float64() < p))
+			}
+			sequences[i] = append(sequences[i], observations)
+		}
+	}
+
+	umbrella := NewNode("Umbrella", []string{"Rain"}, map[string]float64{"T": 0.7, "F": 0.4})
+	h := NewHMM(
+		NewRootNode("Rain", 0.6),
+		NewNode("Rain", []string{Previous("Rain")}, map[string]float64{"T": 0.6, "F": 0.4}),
+		umbrella,
+		NewNode("Wet", []string{"Rain"}, map[string]float64{"T": 0.5, "F": 0.3}),
+	)
+
+	// EM never decreases the likelihood
+	previous := math.Inf(-1)
+	for i := 0; i < 10; i++ {
+		ll, err := h.BaumWelch(sequences, 1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ll < previous-1e-9 {
+			t.Fatalf("iteration %d: log-likelihood decreased %.4f -> %.4f", i, previous, ll)
+		}
+		previous = ll
+	}
+
+	trained, err := h.BaumWelch(sequences, 200, 1e-6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := 0.0
+	for _, sequence := range sequences {
+		ll, err := truth.LogLikelihood(sequence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp += ll
+	}
+	// the HMM trains copies of the nodes
+	if umbrella.cpt["T"] != 0.7 {
+		t.Errorf("Umbrella[T]: Exp 0.7 != %.4f Act", umbrella.cpt["T"])
+	}
+
+	// the maximum likelihood model fits its training data
+	// at least about as well as the true model
+	if trained < exp-1 {
+		t.Errorf("trained log-likelihood %.2f < %.2f of the true model", trained, exp)
+	}
+}
+
+// 0 for true, 1 for false
+func boolBit(b bool) int {
+	if b {
+		return 0
+	}
+	return 1
+}
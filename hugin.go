@@ -0,0 +1,668 @@
+package BayesianNetwork
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Hugin NET language (.net files), as described in
+// the "HUGIN API Reference Manual", chapter 13
+// - only discrete chance nodes with two states are supported.
+//   continuous, decision, utility and function nodes,
+//   classes and functional potentials are reported as errors
+
+// tolerance on the sum of the entries of a data row
+const huginTolerance = 1e-3
+
+// Decodes a Hugin .net specification into a network
+// - the first of the two states of a node is mapped onto "T"
+// - attributes of the net block and the node blocks are
+//   kept as properties, the "name" attribute of the net
+//   block becomes the name of the network, and that of a
+//   node block the name of the node, see WriteHugin
+func ReadHugin(r io.Reader) (*BayesianNetwork, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &huginParser{lex: newHuginLexer(src)}
+	spec, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("Hugin: %v", err)
+	}
+
+	// names that are not identifiers are kept in the name attribute
+	names := make(map[string]string, len(spec.nodes))
+	for id, decl := range spec.nodes {
+		names[id] = id
+		if name, ok := decl.attributes["name"]; ok {
+			names[id] = name
+			delete(decl.attributes, "name")
+		}
+	}
+
+	nodes := make(BayNodes, 0, len(spec.nodes))
+	for _, name := range spec.order {
+		decl := spec.nodes[name]
+		pot, ok := spec.potentials[name]
+		if !ok {
+			return nil, fmt.Errorf("Hugin: node '%s' has no potential", name)
+		}
+
+		pot.child = names[pot.child]
+		for i, parent := range pot.parents {
+			if _, ok := names[parent]; ok {
+				pot.parents[i] = names[parent]
+			}
+		}
+		node, err := pot.node()
+		if err != nil {
+			return nil, fmt.Errorf("Hugin: %v", err)
+		}
+		node.SetStates(decl.states[0], decl.states[1])
+		for key, value := range decl.attributes {
+			node.SetProperty(key, value)
+		}
+		nodes = append(nodes, node)
+	}
+
+	for name := range spec.potentials {
+		if _, ok := spec.nodes[name]; !ok {
+			return nil, fmt.Errorf("Hugin: potential for undeclared node '%s'", name)
+		}
+	}
+
+	bn, err := buildBayesianNetwork(nodes...)
+	if err != nil {
+		return nil, fmt.Errorf("Hugin: %v", err)
+	}
+
+	for key, value := range spec.net {
+		if key == "name" {
+			bn.SetName(value)
+			continue
+		}
+		bn.SetProperty(key, value)
+	}
+
+	return bn, nil
+}
+
+// Encodes the network in the Hugin NET language
+// - nodes and potentials are written in the index order of the network
+// - a node name that is not an identifier, e.g. "Y>=1", is written
+//   as an identifier, with the name in the "name" attribute
+func (bn *BayesianNetwork) WriteHugin(w io.Writer) error {
+	if err := bn.checkDiscrete(); err != nil {
+		return err
+	}
+	ids := make(map[string]string, len(bn.nodeIndex))
+	taken := make(map[string]bool, len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		id := huginIdentifier(node.Name())
+		if taken[id] {
+			return fmt.Errorf("Hugin: '%s' and another node are both written as '%s'", node.Name(), id)
+		}
+		ids[node.Name()] = id
+		taken[id] = true
+	}
+	var buf bytes.Buffer
+
+	buf.WriteString("net\n{\n")
+	if bn.name != "" {
+		fmt.Fprintf(&buf, "    name = %s;\n", huginQuote(bn.name))
+	}
+	writeHuginAttributes(&buf, bn.properties)
+	buf.WriteString("}\n")
+
+	for _, node := range bn.nodeIndex {
+		states := node.States()
+		fmt.Fprintf(&buf, "\nnode %s\n{\n", ids[node.Name()])
+		fmt.Fprintf(&buf, "    states = (%s %s);\n", huginQuote(states[0]), huginQuote(states[1]))
+		attributes := node.Properties()
+		if ids[node.Name()] != node.Name() {
+			attributes = map[string]string{"name": node.Name()}
+			for key, value := range node.Properties() {
+				attributes[key] = value
+			}
+		}
+		writeHuginAttributes(&buf, attributes)
+		buf.WriteString("}\n")
+	}
+
+	for _, node := range bn.nodeIndex {
+		buf.WriteString("\npotential (")
+		buf.WriteString(ids[node.Name()])
+		if parents := node.GetParentNames(); len(parents) > 0 {
+			buf.WriteString(" |")
+			for _, parent := range parents {
+				buf.WriteString(" " + ids[parent])
+			}
+		}
+		buf.WriteString(")\n{\n    data = ")
+
+		keys := node.rowKeys()
+		data := make([]string, 0, len(keys))
+		for _, key := range keys {
+			p := node.prob(key)
+			data = append(data, fmt.Sprintf("(%s %s)", formatProb(p), formatProb(1-p)))
+		}
+		buf.WriteString(nestHuginData(data, len(node.GetParentNames())))
+		buf.WriteString(";\n}\n")
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// wraps the rows of a potential in one level of
+// parentheses per parent: ((r1 r2) (r3 r4))
+func nestHuginData(rows []string, depth int) string {
+	if depth == 0 {
+		return rows[0]
+	}
+	half := len(rows) / 2
+	return "(" + nestHuginData(rows[:half], depth-1) + " " + nestHuginData(rows[half:], depth-1) + ")"
+}
+
+// writes attributes in sorted order, quoting the values
+// that are neither numbers nor lists
+func writeHuginAttributes(buf *bytes.Buffer, attributes map[string]string) {
+	for _, prop := range joinProperties(attributes) {
+		key, value := splitProperty(prop)
+		if !huginIsRaw(value) {
+			value = huginQuote(value)
+		}
+		fmt.Fprintf(buf, "    %s = %s;\n", key, value)
+	}
+}
+
+// the name with every character that is not a letter,
+// digit or underscore replaced by an underscore
+func huginIdentifier(name string) string {
+	id := []rune(name)
+	for i, c := range id {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+			id[i] = '_'
+		}
+	}
+	if len(id) == 0 || unicode.IsDigit(id[0]) {
+		id = append([]rune{'_'}, id...)
+	}
+	return string(id)
+}
+
+func huginIsRaw(value string) bool {
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		return true
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
+
+func huginQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return `"` + s + `"`
+}
+
+// ******* parsed specification *******
+
+type huginSpec struct {
+	net        map[string]string
+	order      []string
+	nodes      map[string]*huginNode
+	potentials map[string]*huginPotential
+}
+
+type huginNode struct {
+	name       string
+	states     []string
+	attributes map[string]string
+}
+
+type huginPotential struct {
+	child   string
+	parents []string
+	data    []float64
+}
+
+// converts the potential into a node
+// - the data lists, for every parent configuration with
+//   the last parent varying fastest, the probability of
+//   each state of the child
+func (pot *huginPotential) node() (*Node, error) {
+	rows := 1 << uint(len(pot.parents))
+	if len(pot.data) != 2*rows {
+		return nil, fmt.Errorf("potential of '%s' has %d entries, expected %d",
+			pot.child, len(pot.data), 2*rows)
+	}
+
+	probs := make([]float64, rows)
+	for i := range probs {
+		t, f := pot.data[2*i], pot.data[2*i+1]
+		if t < 0 || f < 0 || math.Abs(t+f-1) > huginTolerance {
+			return nil, fmt.Errorf("row %d of the potential of '%s' is not a distribution: (%v, %v)",
+				i, pot.child, t, f)
+		}
+		probs[i] = t / (t + f)
+	}
+
+	if len(pot.parents) == 0 {
+		return NewRootNode(pot.child, probs[0]), nil
+	}
+
+	cpt := make(map[string]float64, rows)
+	for i, key := range cptKeys(len(pot.parents)) {
+		cpt[key] = probs[i]
+	}
+	return NewNode(pot.child, pot.parents, cpt), nil
+}
+
+// value of an attribute: a string, number, identifier or
+// a parenthesized list of values
+type huginValue struct {
+	text   string
+	quoted bool
+	list   []huginValue
+	isList bool
+}
+
+// renders the value as it is stored in a property
+// - top-level strings lose their quotes
+func (v huginValue) property() string {
+	if v.isList {
+		return v.String()
+	}
+	return v.text
+}
+
+func (v huginValue) String() string {
+	if !v.isList {
+		if v.quoted {
+			return huginQuote(v.text)
+		}
+		return v.text
+	}
+	parts := make([]string, len(v.list))
+	for i, e := range v.list {
+		parts[i] = e.String()
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// appends every number in the (nested) value to nums
+func (v huginValue) flatten(nums []float64) ([]float64, error) {
+	if !v.isList {
+		f, err := strconv.ParseFloat(v.text, 64)
+		if err != nil || v.quoted {
+			return nil, fmt.Errorf("expected a number, got '%s'", v.text)
+		}
+		return append(nums, f), nil
+	}
+	for _, e := range v.list {
+		var err error
+		if nums, err = e.flatten(nums); err != nil {
+			return nil, err
+		}
+	}
+	return nums, nil
+}
+
+// ******* parser *******
+
+type huginParser struct {
+	lex *huginLexer
+	tok huginToken
+}
+
+func (p *huginParser) next() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *huginParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.tok.line, fmt.Sprintf(format, args...))
+}
+
+func (p *huginParser) expect(text string) error {
+	if p.tok.kind != huginPunct || p.tok.text != text {
+		return p.errorf("expected '%s', got '%s'", text, p.tok.text)
+	}
+	return p.next()
+}
+
+func (p *huginParser) ident() (string, error) {
+	if p.tok.kind != huginIdent {
+		return "", p.errorf("expected an identifier, got '%s'", p.tok.text)
+	}
+	name := p.tok.text
+	return name, p.next()
+}
+
+func (p *huginParser) parse() (*huginSpec, error) {
+	spec := &huginSpec{
+		net:        make(map[string]string),
+		nodes:      make(map[string]*huginNode),
+		potentials: make(map[string]*huginPotential),
+	}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind != huginEOF {
+		if p.tok.kind != huginIdent {
+			return nil, p.errorf("unexpected '%s'", p.tok.text)
+		}
+
+		switch p.tok.text {
+		case "net":
+			if err := p.parseNet(spec); err != nil {
+				return nil, err
+			}
+		case "node", "discrete":
+			if err := p.parseNode(spec); err != nil {
+				return nil, err
+			}
+		case "potential":
+			if err := p.parsePotential(spec); err != nil {
+				return nil, err
+			}
+		case "continuous", "decision", "utility", "function":
+			return nil, p.errorf("%s nodes are not supported", p.tok.text)
+		case "class":
+			return nil, p.errorf("object-oriented classes are not supported")
+		default:
+			return nil, p.errorf("unexpected '%s'", p.tok.text)
+		}
+	}
+
+	return spec, nil
+}
+
+func (p *huginParser) parseNet(spec *huginSpec) error {
+	if err := p.next(); err != nil {
+		return err
+	}
+	attributes, err := p.parseAttributes()
+	if err != nil {
+		return err
+	}
+	for key, value := range attributes {
+		spec.net[key] = value.property()
+	}
+	return nil
+}
+
+// [discrete] node <name> { <attributes> }
+func (p *huginParser) parseNode(spec *huginSpec) error {
+	if p.tok.text == "discrete" {
+		if err := p.next(); err != nil {
+			return err
+		}
+		if p.tok.kind != huginIdent || p.tok.text != "node" {
+			return p.errorf("discrete %s nodes are not supported", p.tok.text)
+		}
+	}
+	if err := p.next(); err != nil {
+		return err
+	}
+
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if _, ok := spec.nodes[name]; ok {
+		return p.errorf("duplicate node '%s'", name)
+	}
+
+	attributes, err := p.parseAttributes()
+	if err != nil {
+		return err
+	}
+
+	node := &huginNode{
+		name:       name,
+		attributes: make(map[string]string),
+	}
+	for key, value := range attributes {
+		if key != "states" {
+			node.attributes[key] = value.property()
+			continue
+		}
+		for _, state := range value.list {
+			node.states = append(node.states, state.text)
+		}
+	}
+	if len(node.states) != 2 {
+		return fmt.Errorf("node '%s' has %d states, only binary nodes are supported",
+			name, len(node.states))
+	}
+
+	spec.order = append(spec.order, name)
+	spec.nodes[name] = node
+	return nil
+}
+
+// potential ( <child> [| <parents>] ) { data = ...; }
+func (p *huginParser) parsePotential(spec *huginSpec) error {
+	if err := p.next(); err != nil {
+		return err
+	}
+	if err := p.expect("("); err != nil {
+		return err
+	}
+
+	pot := &huginPotential{}
+	var heads []string
+	for p.tok.kind == huginIdent {
+		heads = append(heads, p.tok.text)
+		if err := p.next(); err != nil {
+			return err
+		}
+	}
+	if len(heads) != 1 {
+		return p.errorf("potentials must have exactly one child node, got %v", heads)
+	}
+	pot.child = heads[0]
+
+	if p.tok.kind == huginPunct && p.tok.text == "|" {
+		if err := p.next(); err != nil {
+			return err
+		}
+		for p.tok.kind == huginIdent {
+			pot.parents = append(pot.parents, p.tok.text)
+			if err := p.next(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return err
+	}
+
+	if _, ok := spec.potentials[pot.child]; ok {
+		return p.errorf("duplicate potential for '%s'", pot.child)
+	}
+
+	attributes, err := p.parseAttributes()
+	if err != nil {
+		return err
+	}
+	for key := range attributes {
+		switch key {
+		case "data", "experience", "fading":
+		case "model_nodes", "model_data":
+			return fmt.Errorf("potential of '%s': functional potentials (%s) are not supported",
+				pot.child, key)
+		}
+	}
+
+	data, ok := attributes["data"]
+	if !ok {
+		return fmt.Errorf("potential of '%s' has no data", pot.child)
+	}
+	if pot.data, err = data.flatten(nil); err != nil {
+		return fmt.Errorf("potential of '%s': %v", pot.child, err)
+	}
+
+	spec.potentials[pot.child] = pot
+	return nil
+}
+
+// { <name> = <value>; ... }
+func (p *huginParser) parseAttributes() (map[string]huginValue, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	attributes := make(map[string]huginValue)
+	for !(p.tok.kind == huginPunct && p.tok.text == "}") {
+		key, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(";"); err != nil {
+			return nil, err
+		}
+		attributes[key] = value
+	}
+
+	return attributes, p.next()
+}
+
+func (p *huginParser) parseValue() (huginValue, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == huginPunct && tok.text == "(":
+		if err := p.next(); err != nil {
+			return huginValue{}, err
+		}
+		v := huginValue{isList: true}
+		for !(p.tok.kind == huginPunct && p.tok.text == ")") {
+			e, err := p.parseValue()
+			if err != nil {
+				return huginValue{}, err
+			}
+			v.list = append(v.list, e)
+		}
+		return v, p.next()
+	case tok.kind == huginString, tok.kind == huginIdent:
+		v := huginValue{text: tok.text, quoted: tok.kind == huginString}
+		return v, p.next()
+	}
+	return huginValue{}, p.errorf("unexpected '%s'", tok.text)
+}
+
+// ******* lexer *******
+
+const (
+	huginEOF = iota
+	huginIdent
+	huginString
+	huginPunct
+)
+
+type huginToken struct {
+	kind int
+	text string
+	line int
+}
+
+type huginLexer struct {
+	src  []byte
+	pos  int
+	line int
+}
+
+func newHuginLexer(src []byte) *huginLexer {
+	return &huginLexer{src: src, line: 1}
+}
+
+// returns the next token
+// - identifiers and numbers are both returned as huginIdent
+// - comments run from '%' to the end of the line
+func (l *huginLexer) next() (huginToken, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '\n' {
+			l.line++
+		}
+		if c == '%' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+			continue
+		}
+		if !unicode.IsSpace(rune(c)) {
+			break
+		}
+		l.pos++
+	}
+
+	if l.pos >= len(l.src) {
+		return huginToken{kind: huginEOF, text: "EOF", line: l.line}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("{}()=;|", c) >= 0:
+		l.pos++
+		return huginToken{kind: huginPunct, text: string(c), line: l.line}, nil
+	case c == '"':
+		return l.string()
+	}
+
+	start := l.pos
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if unicode.IsSpace(rune(c)) || c == '"' || c == '%' || strings.IndexByte("{}()=;|", c) >= 0 {
+			break
+		}
+		l.pos++
+	}
+	return huginToken{kind: huginIdent, text: string(l.src[start:l.pos]), line: l.line}, nil
+}
+
+func (l *huginLexer) string() (huginToken, error) {
+	line := l.line
+	l.pos++
+
+	var buf bytes.Buffer
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		l.pos++
+		switch c {
+		case '"':
+			return huginToken{kind: huginString, text: buf.String(), line: line}, nil
+		case '\\':
+			if l.pos < len(l.src) {
+				c = l.src[l.pos]
+				l.pos++
+			}
+		case '\n':
+			l.line++
+		}
+		buf.WriteByte(c)
+	}
+	return huginToken{}, fmt.Errorf("line %d: unterminated string", line)
+}
@@ -0,0 +1,137 @@
+package BayesianNetwork
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+const huginAsia = `
+% a cut-down version of the Asia network
+net
+{
+    node_size = (80 40);
+}
+
+node asia
+{
+    label = "Visit to Asia?";
+    position = (400 750);
+    states = ("yes" "no");
+}
+
+node tub
+{
+    label = "Has tuberculosis";
+    states = ("yes" "no");
+}
+
+discrete node either
+{
+    states = ("yes" "no");
+}
+
+node lung
+{
+    states = ("yes" "no");
+}
+
+potential (asia)
+{
+    data = (0.01 0.99);
+}
+
+potential (tub | asia)
+{
+    data = ((0.05 0.95)	%  asia=yes
+	    (0.01 0.99));	%  asia=no
+}
+
+potential (lung)
+{
+    data = (0.1 0.9);
+}
+
+potential (either | lung tub)
+{
+    data = (((1 0) (1 0)) ((1 0) (0 1)));
+}
+`
+
+func TestReadHugin(t *testing.T) {
+	bn, err := ReadHugin(strings.NewReader(huginAsia))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bn.NodeCount() != 4 {
+		t.Fatalf("expected 4 nodes, got %d", bn.NodeCount())
+	}
+	if bn.Property("node_size") != "(80 40)" {
+		t.Errorf("node_size: %q", bn.Property("node_size"))
+	}
+
+	asia := bn.GetNode("asia")
+	if asia.Property("label") != "Visit to Asia?" {
+		t.Errorf("label: %q", asia.Property("label"))
+	}
+
+	either := bn.GetNode("either")
+	exp := map[string]float64{"TT": 1, "TF": 1, "FT": 1, "FF": 0}
+	for key, p := range exp {
+		if either.cpt[key] != p {
+			t.Errorf("either[%s] = %v, expected %v", key, either.cpt[key], p)
+		}
+	}
+	if math.Abs(bn.GetNode("tub").cpt["F"]-0.01) > 1e-12 {
+		t.Errorf("tub[F] = %v", bn.GetNode("tub").cpt["F"])
+	}
+}
+
+func TestHuginRoundTrip(t *testing.T) {
+	bn := BuildStudentNetwork()
+	bn.SetName("Student")
+	bn.GetNode("E").SetProperty("label", `Exam "hard"`)
+
+	var buf bytes.Buffer
+	if err := bn.WriteHugin(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := ReadHugin(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Name() != "Student" {
+		t.Errorf("name: %q", decoded.Name())
+	}
+	if decoded.GetNode("E").Property("label") != `Exam "hard"` {
+		t.Errorf("label: %q", decoded.GetNode("E").Property("label"))
+	}
+	for _, node := range bn.GetNodes() {
+		other := decoded.GetNode(node.Name())
+		for _, key := range node.rowKeys() {
+			if math.Abs(node.cpt[key]-other.cpt[key]) > 1e-12 {
+				t.Errorf("%s[%s]: %v != %v", node.Name(), key, node.cpt[key], other.cpt[key])
+			}
+		}
+	}
+}
+
+func TestReadHuginUnsupported(t *testing.T) {
+	specs := []string{
+		"continuous node x { }",
+		"decision node d { states = (\"a\" \"b\"); }",
+		"utility node u { }",
+		"node a { states = (\"x\" \"y\" \"z\"); }",
+		"node a { states = (\"x\" \"y\"); } potential (a) { model_nodes = (); model_data = (0); }",
+	}
+
+	for _, spec := range specs {
+		if _, err := ReadHugin(strings.NewReader(spec)); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
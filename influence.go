@@ -0,0 +1,364 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+)
+
+// Influence diagrams: chance Nodes extended with decision and
+// utility nodes.
+// - decisions are binary like every other variable, the options
+//   are named like the states of a Node
+// - decisions are made in the order they are given; a decision
+//   remembers every earlier decision and observation (no-forgetting)
+// - the solver enumerates the information set of every decision,
+//   so it is exponential in the size of the information sets
+
+// A decision node. The informational parents are the chance nodes
+// and earlier decisions observed before the decision is made
+type DecisionNode struct {
+	name          string
+	informational []string
+	// names of the "T" and "F" options, empty <=> "T"/"F"
+	options [2]string
+}
+
+func NewDecisionNode(name string, informational []string) *DecisionNode {
+	return &DecisionNode{
+		name:          name,
+		informational: informational,
+	}
+}
+
+func (self *DecisionNode) Name() string {
+	return self.name
+}
+
+func (self *DecisionNode) GetInformationalParents() []string {
+	return self.informational
+}
+
+// returns the names of the options, the name of
+// the "T" option first and the "F" option second
+func (self *DecisionNode) Options() []string {
+	if self.options[0] == "" {
+		return []string{"T", "F"}
+	}
+	return []string{self.options[0], self.options[1]}
+}
+
+// names the "T" and "F" options of the decision
+func (self *DecisionNode) SetOptions(t, f string) {
+	self.options = [2]string{t, f}
+}
+
+// A utility node: a utility for every assignment of its parents,
+// keyed like a CPT. Without parents the single key is ""
+type UtilityNode struct {
+	name        string
+	parentNames []string
+	utilities   map[string]float64
+}
+
+func NewUtilityNode(name string, parents []string, utilities map[string]float64) *UtilityNode {
+	return &UtilityNode{
+		name:        name,
+		parentNames: parents,
+		utilities:   utilities,
+	}
+}
+
+func (self *UtilityNode) Name() string {
+	return self.name
+}
+
+func (self *UtilityNode) GetParentNames() []string {
+	return self.parentNames
+}
+
+// the utility of the given parent assignment
+func (self *UtilityNode) Utility(key string) float64 {
+	return self.utilities[key]
+}
+
+// The optimal policy of a decision: the option to take for every
+// assignment of its information set, keyed like a CPT over the
+// information set
+type Strategy struct {
+	Decision string
+	// the earlier decisions and observations
+	// the policy depends on, in key order
+	Information []string
+	// the option to take, "T" or "F"
+	Policy map[string]string
+	// the expected utility of the "T" and "F" option
+	ExpectedUtility map[string][]float64
+}
+
+type InfluenceDiagram struct {
+	chance    BayNodes
+	decisions []*DecisionNode
+	utilities []*UtilityNode
+	// the chance nodes, with every decision as a root
+	network *BayesianNetwork
+}
+
+// Creates an influence diagram, the decisions are made in the
+// given order. Chance nodes may have decisions as parents
+// - panics if the diagram is invalid
+func NewInfluenceDiagram(chance BayNodes, decisions []*DecisionNode, utilities []*UtilityNode) *InfluenceDiagram {
+	id, err := buildInfluenceDiagram(chance, decisions, utilities)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+func buildInfluenceDiagram(chance BayNodes, decisions []*DecisionNode, utilities []*UtilityNode) (*InfluenceDiagram, error) {
+	id := &InfluenceDiagram{
+		chance:    chance,
+		decisions: decisions,
+		utilities: utilities,
+	}
+
+	var err error
+	id.network, err = id.policyNetwork(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make(map[string]int, len(decisions))
+	for k, d := range decisions {
+		order[d.name] = k
+	}
+
+	for k, d := range decisions {
+		for _, parent := range d.informational {
+			if id.network.nodes[parent] == nil {
+				return nil, fmt.Errorf("informational parent '%s' of '%s' does not exist", parent, d.name)
+			}
+			if j, ok := order[parent]; ok && j >= k {
+				return nil, fmt.Errorf("'%s' cannot observe the later decision '%s'", d.name, parent)
+			}
+			// an observation cannot depend on the current or a later decision
+			for _, later := range decisions[k:] {
+				for _, descendant := range id.network.descendants(id.network.nodes[later.name]) {
+					if descendant.Name() == parent {
+						return nil, fmt.Errorf("'%s' cannot observe '%s', which depends on '%s'", d.name, parent, later.name)
+					}
+				}
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, u := range utilities {
+		if seen[u.name] || id.network.nodes[u.name] != nil {
+			return nil, fmt.Errorf("Duplicate nodeName: %s", u.name)
+		}
+		seen[u.name] = true
+		for _, key := range cptKeys(len(u.parentNames)) {
+			if _, ok := u.utilities[key]; !ok {
+				return nil, fmt.Errorf("utility node '%s' has no utility for '%s'", u.name, key)
+			}
+		}
+		for _, parent := range u.parentNames {
+			if id.network.nodes[parent] == nil {
+				return nil, fmt.Errorf("parent '%s' of utility node '%s' does not exist", parent, u.name)
+			}
+		}
+	}
+
+	return id, nil
+}
+
+// Solves the diagram by backward induction: the last decision is
+// optimized first, the earlier decisions assume the later ones
+// follow their optimal policy. Returns the strategy of every
+// decision, in the order of the decisions, along with the
+// maximum expected utility
+func (id *InfluenceDiagram) Solve() ([]Strategy, float64, error) {
+	return id.solve(nil)
+}
+
+// Solve given evidence on chance nodes that are known
+// before any decision is made
+func (id *InfluenceDiagram) solve(evidence map[string]string) ([]Strategy, float64, error) {
+	for name := range evidence {
+		for _, d := range id.decisions {
+			if d.name == name {
+				return nil, 0, fmt.Errorf("evidence on the decision '%s'", name)
+			}
+			for _, descendant := range id.network.descendants(id.network.nodes[d.name]) {
+				if descendant.Name() == name {
+					return nil, 0, fmt.Errorf("evidence on '%s', which depends on '%s'", name, d.name)
+				}
+			}
+		}
+	}
+
+	strategies := make([]Strategy, len(id.decisions))
+	for k := len(id.decisions) - 1; k >= 0; k-- {
+		d := id.decisions[k]
+		bn, err := id.policyNetwork(strategies[k+1:])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		info := id.informationSet(k)
+		s := Strategy{
+			Decision:        d.name,
+			Information:     info,
+			Policy:          make(map[string]string),
+			ExpectedUtility: make(map[string][]float64),
+		}
+		keys := cptKeys(len(info))
+		for i, assignment := range assignments(info) {
+			eu := make([]float64, 2)
+			for bit, option := range []string{"T", "F"} {
+				if !consistent(assignment, evidence) {
+					// impossible, the policy does not matter
+					break
+				}
+				eu[bit], err = id.expectedUtility(bn, merge(evidence, with(assignment, d.name, option)))
+				if err != nil {
+					return nil, 0, err
+				}
+			}
+
+			s.ExpectedUtility[keys[i]] = eu
+			s.Policy[keys[i]] = "T"
+			if eu[1] > eu[0] {
+				s.Policy[keys[i]] = "F"
+			}
+		}
+		strategies[k] = s
+	}
+
+	bn, err := id.policyNetwork(strategies)
+	if err != nil {
+		return nil, 0, err
+	}
+	meu, err := id.expectedUtility(bn, evidence)
+	if err != nil {
+		return nil, 0, err
+	}
+	return strategies, meu, nil
+}
+
+// The expected utility of following the strategies, one
+// per decision in the order of the decisions
+func (id *InfluenceDiagram) ExpectedUtility(strategies []Strategy) (float64, error) {
+	if len(strategies) != len(id.decisions) {
+		return 0, fmt.Errorf("%d strategies for %d decisions", len(strategies), len(id.decisions))
+	}
+	bn, err := id.policyNetwork(strategies)
+	if err != nil {
+		return 0, err
+	}
+	return id.expectedUtility(bn, nil)
+}
+
+// the information set of decision k: its informational parents and
+// those of every earlier decision, along with the earlier decisions
+func (id *InfluenceDiagram) informationSet(k int) []string {
+	seen := make(map[string]bool)
+	info := make([]string, 0, 4)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			info = append(info, name)
+		}
+	}
+
+	for _, d := range id.decisions[:k] {
+		add(d.name)
+	}
+	for _, d := range id.decisions[:k+1] {
+		for _, parent := range d.informational {
+			add(parent)
+		}
+	}
+	return info
+}
+
+// the chance nodes, where the decisions with a strategy become
+// deterministic nodes following the policy. The strategies are
+// those of the last decisions, the other decisions become roots
+func (id *InfluenceDiagram) policyNetwork(strategies []Strategy) (*BayesianNetwork, error) {
+	nodes := make(BayNodes, 0, len(id.chance)+len(id.decisions))
+	for _, node := range id.chance {
+		nodes = append(nodes, node.clone())
+	}
+
+	first := len(id.decisions) - len(strategies)
+	for k, d := range id.decisions {
+		if k < first {
+			nodes = append(nodes, NewRootNode(d.name, 0.5))
+			continue
+		}
+
+		s := strategies[k-first]
+		if s.Decision != d.name {
+			return nil, fmt.Errorf("strategy for '%s' given for decision '%s'", s.Decision, d.name)
+		}
+		cpt := make(map[string]float64, len(s.Policy))
+		for _, key := range cptKeys(len(s.Information)) {
+			option, ok := s.Policy[key]
+			if !ok {
+				return nil, fmt.Errorf("strategy for '%s' has no option for '%s'", d.name, key)
+			}
+			if option == "T" {
+				cpt[key] = 1
+			} else {
+				cpt[key] = 0
+			}
+		}
+
+		if len(s.Information) == 0 {
+			nodes = append(nodes, NewRootNode(d.name, cpt[""]))
+		} else {
+			nodes = append(nodes, NewNode(d.name, s.Information, cpt))
+		}
+	}
+
+	return buildBayesianNetwork(nodes...)
+}
+
+// the expected sum of the utilities given the evidence
+// - 0 if the evidence is impossible, its policy does not matter
+func (id *InfluenceDiagram) expectedUtility(bn *BayesianNetwork, evidence map[string]string) (float64, error) {
+	logE, err := bn.LogEvidenceProbability(evidence)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsInf(logE, -1) {
+		return 0, nil
+	}
+
+	eu := 0.0
+	for _, u := range id.utilities {
+		keys := cptKeys(len(u.parentNames))
+		for i, assignment := range assignments(u.parentNames) {
+			if !consistent(assignment, evidence) {
+				continue
+			}
+			logP, err := bn.LogEvidenceProbability(merge(evidence, assignment))
+			if err != nil {
+				return 0, err
+			}
+			eu += u.utilities[keys[i]] * math.Exp(logP-logE)
+		}
+	}
+	return eu, nil
+}
+
+// reports whether the assignments agree on their common names
+func consistent(a, b map[string]string) bool {
+	for name, value := range a {
+		if v, ok := b[name]; ok && v != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+// whether to buy a forecast, and whether to take an umbrella
+// given the forecast. The forecast is uninformative if not bought
+func BuildUmbrellaDecision() *InfluenceDiagram {
+	chance := BayNodes{
+		NewRootNode("Rain", 0.3),
+		NewNode("Forecast", []string{"Rain", "Buy"}, map[string]float64{
+			"TT": 0.8, "TF": 0.5, "FT": 0.1, "FF": 0.5}),
+	}
+	buy := NewDecisionNode("Buy", nil)
+	take := NewDecisionNode("Take", []string{"Forecast"})
+	take.SetOptions("take", "leave")
+
+	utilities := []*UtilityNode{
+		NewUtilityNode("Comfort", []string{"Rain", "Take"}, map[string]float64{
+			"TT": 70, "TF": 0, "FT": 20, "FF": 100}),
+		NewUtilityNode("Cost", []string{"Buy"}, map[string]float64{"T": -5, "F": 0}),
+	}
+	return NewInfluenceDiagram(chance, []*DecisionNode{buy, take}, utilities)
+}
+
+func TestSolveInfluenceDiagram(t *testing.T) {
+	id := BuildUmbrellaDecision()
+	strategies, meu, err := id.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// with the forecast: 0.31 * E[U|rainy] + 0.69 * E[U|dry] - 5
+	// = 0.24*70 + 0.07*20 + 0.63*100 - 5 = 76.2, without it: 70
+	if math.Abs(meu-76.2) > 1e-9 {
+		t.Errorf("MEU: Exp %.4f != %.4f Act", 76.2, meu)
+	}
+	if strategies[0].Policy[""] != "T" {
+		t.Errorf("the forecast should be bought: %v", strategies[0].Policy)
+	}
+
+	take := strategies[1]
+	if len(take.Information) != 2 || take.Information[0] != "Buy" || take.Information[1] != "Forecast" {
+		t.Fatalf("Exp [Buy Forecast] != %v Act information", take.Information)
+	}
+	for key, exp := range map[string]string{"TT": "T", "TF": "F", "FT": "F", "FF": "F"} {
+		if take.Policy[key] != exp {
+			t.Errorf("Take given Buy, Forecast = %s: Exp %s != %s Act", key, exp, take.Policy[key])
+		}
+	}
+	// E[U | Buy, rainy forecast, take] = (0.24*70 + 0.07*20) / 0.31 - 5
+	if eu := take.ExpectedUtility["TT"][0]; math.Abs(eu-(18.2/0.31-5)) > 1e-9 {
+		t.Errorf("Exp %.4f != %.4f Act", 18.2/0.31-5, eu)
+	}
+
+	// never buying the forecast and always leaving the umbrella
+	never := []Strategy{
+		{Decision: "Buy", Policy: map[string]string{"": "F"}},
+		{Decision: "Take", Information: []string{"Forecast"},
+			Policy: map[string]string{"T": "F", "F": "F"}},
+	}
+	eu, err := id.ExpectedUtility(never)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(eu-70) > 1e-9 {
+		t.Errorf("Exp %.4f != %.4f Act", 70.0, eu)
+	}
+}
+
+func TestInvalidInfluenceDiagram(t *testing.T) {
+	chance := BayNodes{NewNode("Outcome", []string{"Act"}, map[string]float64{"T": 0.9, "F": 0.1})}
+	act := NewDecisionNode("Act", []string{"Outcome"})
+	if _, err := buildInfluenceDiagram(chance, []*DecisionNode{act}, nil); err == nil {
+		t.Errorf("expected an error for observing the outcome of the decision")
+	}
+
+	chance = BayNodes{NewNode("Outcome", []string{"Act"}, map[string]float64{"T": 0.9, "F": 0.1})}
+	act = NewDecisionNode("Act", nil)
+	u := NewUtilityNode("U", []string{"Outcome"}, map[string]float64{"T": 1})
+	if _, err := buildInfluenceDiagram(chance, []*DecisionNode{act}, []*UtilityNode{u}); err == nil {
+		t.Errorf("expected an error for a missing utility")
+	}
+}
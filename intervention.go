@@ -0,0 +1,96 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+)
+
+// An inference method computes the posterior marginals of every
+// node given the evidence, e.g. (*BayesianNetwork).ExactInference
+type InferenceMethod func(bn *BayesianNetwork, evidence map[string]string) (StatMap, error)
+
+// wraps GibbsSampling as an InferenceMethod
+func GibbsMethod(burnIn, samples int) InferenceMethod {
+	return func(bn *BayesianNetwork, evidence map[string]string) (StatMap, error) {
+		return bn.GibbsSampling(evidence, burnIn, samples), nil
+	}
+}
+
+// Returns a copy of the network
+// - the nodes are copied, so the copy can be modified and
+//   sampled independently of the original
+func (bn *BayesianNetwork) Copy() *BayesianNetwork {
+	return bn.Intervene(nil)
+}
+
+// Returns the mutilated network for the intervention do(X=x):
+// every intervened node loses its incoming edges and becomes a
+// root that takes its forced value with probability 1.
+// e.g. bn.Intervene(map[string]string{"P": "T"})
+// - the original network is not modified
+// - panics if an intervened node does not exist in the network
+func (bn *BayesianNetwork) Intervene(do map[string]string) *BayesianNetwork {
+	for name, value := range do {
+		if bn.nodes[name] == nil {
+			panic(fmt.Sprintf("Node '%s' does not exist in network", name))
+		}
+		if _, err := stateBit(value); err != nil {
+			panic(fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	nodes := make(BayNodes, 0, len(bn.nodeIndex))
+	for _, node := range bn.nodeIndex {
+		value, ok := do[node.Name()]
+		if !ok {
+			nodes = append(nodes, node.clone())
+			continue
+		}
+
+		p := 0.0
+		if value == "T" {
+			p = 1.0
+		}
+		forced := NewRootNode(node.Name(), p)
+		forced.states = node.states
+		forced.properties = node.clone().properties
+		nodes = append(nodes, forced)
+	}
+
+	return mustBuild(bn, nodes)
+}
+
+// Answers P(Y | do(X=x), evidence) for every node Y with the given
+// inference method, by running it on the mutilated network with the
+// intervened values clamped as evidence
+// e.g. bn.InterventionalQuery(do, evidence, (*BayesianNetwork).ExactInference)
+func (bn *BayesianNetwork) InterventionalQuery(do, evidence map[string]string, method InferenceMethod) (StatMap, error) {
+	if _, err := bn.evidenceBits(do); err != nil {
+		return nil, err
+	}
+
+	clamped := make(map[string]string, len(do)+len(evidence))
+	for name, value := range evidence {
+		clamped[name] = value
+	}
+	for name, value := range do {
+		if e, ok := evidence[name]; ok && e != value {
+			return nil, fmt.Errorf("%s: evidence '%s' contradicts do(%s=%s)", name, e, name, value)
+		}
+		clamped[name] = value
+	}
+
+	return method(bn.Intervene(do), clamped)
+}
+
+// builds the derived network, copying the metadata of bn
+func mustBuild(bn *BayesianNetwork, nodes BayNodes) *BayesianNetwork {
+	derived, err := buildBayesianNetwork(nodes...)
+	if err != nil {
+		panic(err)
+	}
+	derived.name = bn.name
+	for key, value := range bn.properties {
+		derived.SetProperty(key, value)
+	}
+	return derived
+}
@@ -0,0 +1,75 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIntervene(t *testing.T) {
+	bn := BuildStudentNetwork()
+	do := map[string]string{"P": "T"}
+
+	mutilated := bn.Intervene(do)
+	if !mutilated.GetNode("P").IsRoot() || mutilated.GetNode("E").NumChildren() != 0 {
+		t.Fatalf("P should have lost its parents:\n%s", mutilated.PrintNetwork())
+	}
+	if bn.GetNode("P").IsRoot() {
+		t.Fatal("the original network was modified")
+	}
+
+	exact := (*BayesianNetwork).ExactInference
+	interventional, err := bn.InterventionalQuery(do, nil, exact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	observational, err := bn.ExactInference(do)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prior, err := bn.ExactInference(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// forcing P leaves its causes untouched, observing it does not
+	if math.Abs(interventional["E"][0]-prior["E"][0]) > 1e-9 {
+		t.Errorf("P(E|do(P=T)) = %.4f != P(E) = %.4f", interventional["E"][0], prior["E"][0])
+	}
+	if math.Abs(observational["E"][0]-prior["E"][0]) < 1e-3 {
+		t.Errorf("P(E|P=T) = %.4f should differ from P(E)", observational["E"][0])
+	}
+
+	// P(U=T|do(P=T)) = sum_r P(r) P(U=T|P=T,r)
+	u := bn.GetNode("U")
+	exp := prior["R"][0]*u.cpt["TT"] + prior["R"][1]*u.cpt["TF"]
+	if math.Abs(interventional["U"][0]-exp) > 1e-9 {
+		t.Errorf("P(U|do(P=T)): Exp %.4f != %.4f Act", exp, interventional["U"][0])
+	}
+
+	if _, err := bn.InterventionalQuery(do, map[string]string{"P": "F"}, exact); err == nil {
+		t.Error("expected an error for evidence contradicting the intervention")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	nodes := BayNodes{NewLogisticNode("Mowed", []string{"Fertilized"}, -1, []float64{2})}
+	for _, node := range BuildLawnNetwork().GetNodes() {
+		nodes = append(nodes, node.clone())
+	}
+	bn := NewBayesianNetwork(nodes...)
+	cp := bn.Copy()
+
+	cp.GetNode("Growth").GetConditionalLinearGaussian("T").Weights[0] = 7
+	cp.GetNode("Temp").GetLinearGaussian().Intercept = 0
+	cp.GetNode("Mowed").GetCPD().(*LogisticCPD).Weights[0] = 0
+
+	if w := bn.GetNode("Growth").GetConditionalLinearGaussian("T").Weights[0]; w != 0.5 {
+		t.Errorf("Growth: Exp weight 0.5 != %v Act", w)
+	}
+	if m := bn.GetNode("Temp").GetLinearGaussian().Intercept; m != 20 {
+		t.Errorf("Temp: Exp intercept 20 != %v Act", m)
+	}
+	if w := bn.GetNode("Mowed").GetCPD().(*LogisticCPD).Weights[0]; w != 2 {
+		t.Errorf("Mowed: Exp weight 2 != %v Act", w)
+	}
+}
@@ -0,0 +1,176 @@
+package BayesianNetwork
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// JSON encoding of networks and nodes.
+//
+// A network is an object with an optional name and metadata,
+// and its nodes in index order:
+//
+//	{
+//	  "name": "Student",
+//	  "metadata": {"source": "Koller & Friedman"},
+//	  "nodes": [
+//	    {
+//	      "name": "E",
+//	      "states": ["T", "F"],
+//	      "cpt": [{"given": "", "p": [0.3, 0.7]}]
+//	    },
+//	    {
+//	      "name": "J",
+//	      "states": ["hired", "rejected"],
+//	      "parents": ["P"],
+//	      "cpt": [
+//	        {"given": "T", "p": [0.7, 0.3]},
+//	        {"given": "F", "p": [0.3, 0.7]}
+//	      ],
+//	      "metadata": {"position": "(1, 2)"}
+//	    }
+//	  ]
+//	}
+//
+// - "states" names the "T" and "F" outcomes, in that order. It
+//   defaults to ["T", "F"]
+// - every CPT row holds the parent assignment it applies to as
+//   a "T"/"F" string in the order of "parents", and the probability
+//   of each of the two states. Root nodes have a single row with
+//   an empty "given"
+// - decoding validates the rows with the same checks as ValidateCPT
+
+// tolerance on the sum of the probabilities of a CPT row
+const jsonTolerance = 1e-6
+
+type jsonNetwork struct {
+	Name     string            `json:"name,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Nodes    []*Node           `json:"nodes"`
+}
+
+type jsonNode struct {
+	Name     string            `json:"name"`
+	States   []string          `json:"states,omitempty"`
+	Parents  []string          `json:"parents,omitempty"`
+	CPT      []jsonRow         `json:"cpt"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type jsonRow struct {
+	Given string    `json:"given"`
+	P     []float64 `json:"p"`
+}
+
+func (bn *BayesianNetwork) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonNetwork{
+		Name:     bn.name,
+		Metadata: bn.properties,
+		Nodes:    bn.nodeIndex,
+	})
+}
+
+// Decodes the network and validates it the same
+// way as NewBayesianNetwork, reporting errors instead
+// of panicking
+func (bn *BayesianNetwork) UnmarshalJSON(data []byte) error {
+	var doc jsonNetwork
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	decoded, err := buildBayesianNetwork(doc.Nodes...)
+	if err != nil {
+		return err
+	}
+	decoded.name = doc.Name
+	decoded.properties = doc.Metadata
+
+	*bn = *decoded
+	return nil
+}
+
+func (self *Node) MarshalJSON() ([]byte, error) {
+	if self.IsContinuous() {
+		return nil, fmt.Errorf("%s is continuous, only binary nodes are supported", self.name)
+	}
+	doc := jsonNode{
+		Name:     self.name,
+		States:   self.States(),
+		Parents:  self.parentNames,
+		Metadata: self.properties,
+	}
+
+	for _, key := range self.rowKeys() {
+		p := self.prob(key)
+		if len(self.parentNames) == 0 {
+			key = ""
+		}
+		doc.CPT = append(doc.CPT, jsonRow{Given: key, P: []float64{p, 1 - p}})
+	}
+
+	return json.Marshal(doc)
+}
+
+// Decodes a node that is not yet part of a network
+func (self *Node) UnmarshalJSON(data []byte) error {
+	var doc jsonNode
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	if doc.Name == "" {
+		return fmt.Errorf("node without a name")
+	}
+	if doc.States != nil && len(doc.States) != 2 {
+		return fmt.Errorf("%s has %d states, only binary nodes are supported", doc.Name, len(doc.States))
+	}
+
+	cpt := make(map[string]float64, len(doc.CPT))
+	for _, row := range doc.CPT {
+		if len(row.P) != 2 {
+			return fmt.Errorf("%s: CPT row '%s' has %d probabilities, expected 2", doc.Name, row.Given, len(row.P))
+		}
+		t, f := row.P[0], row.P[1]
+		if t < 0 || f < 0 || math.Abs(t+f-1) > jsonTolerance {
+			return fmt.Errorf("%s: CPT row '%s' is not a distribution: %v", doc.Name, row.Given, row.P)
+		}
+		if len(row.Given) != len(doc.Parents) {
+			return fmt.Errorf("%s: CPT row '%s' should have one state per parent %v", doc.Name, row.Given, doc.Parents)
+		}
+		for _, c := range row.Given {
+			if c != 'T' && c != 'F' {
+				return fmt.Errorf("%s: invalid CPT row '%s', should consist of T and F", doc.Name, row.Given)
+			}
+		}
+		if _, ok := cpt[row.Given]; ok {
+			return fmt.Errorf("%s: duplicate CPT row '%s'", doc.Name, row.Given)
+		}
+		cpt[row.Given] = t
+	}
+
+	var node *Node
+	if len(doc.Parents) == 0 {
+		p, ok := cpt[""]
+		if !ok || len(cpt) != 1 {
+			return fmt.Errorf("(Root): %s's CPT should have a single row with an empty 'given' (cpt: %v)",
+				doc.Name, cpt)
+		}
+		node = NewRootNode(doc.Name, p)
+	} else {
+		node = NewNode(doc.Name, doc.Parents, cpt)
+	}
+
+	if err := node.validateCPT(len(doc.Parents)); err != nil {
+		return err
+	}
+
+	if doc.States != nil {
+		node.SetStates(doc.States[0], doc.States[1])
+	}
+	node.properties = doc.Metadata
+
+	*self = *node
+	return nil
+}
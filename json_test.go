@@ -0,0 +1,68 @@
+package BayesianNetwork
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	bn := BuildStudentNetwork()
+	bn.SetName("Student")
+	bn.SetProperty("source", "lecture notes")
+	bn.GetNode("J").SetStates("hired", "rejected")
+
+	data, err := json.Marshal(bn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded BayesianNetwork
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Name() != "Student" || decoded.Property("source") != "lecture notes" {
+		t.Errorf("lost network metadata: %s", data)
+	}
+	if s := decoded.GetNode("J").States(); s[0] != "hired" {
+		t.Errorf("states: %v", s)
+	}
+	for _, node := range bn.GetNodes() {
+		other := decoded.GetNode(node.Name())
+		if !other.validateParents(node.GetParentNames()) {
+			t.Errorf("%s: parents %v", node.Name(), other.GetParentNames())
+		}
+		for _, key := range node.rowKeys() {
+			if math.Abs(node.cpt[key]-other.cpt[key]) > 1e-12 {
+				t.Errorf("%s[%s]: %v != %v", node.Name(), key, node.cpt[key], other.cpt[key])
+			}
+		}
+	}
+}
+
+func TestJSONValidation(t *testing.T) {
+	docs := []string{
+		// missing row
+		`{"nodes": [{"name": "A", "cpt": [{"given": "", "p": [0.5, 0.5]}]},
+		            {"name": "B", "parents": ["A"], "cpt": [{"given": "T", "p": [0.5, 0.5]}]}]}`,
+		// row is not a distribution
+		`{"nodes": [{"name": "A", "cpt": [{"given": "", "p": [0.5, 0.6]}]}]}`,
+		// unknown parent
+		`{"nodes": [{"name": "B", "parents": ["A"], "cpt": [{"given": "T", "p": [1, 0]}, {"given": "F", "p": [0, 1]}]}]}`,
+		// wrong key length
+		`{"nodes": [{"name": "A", "cpt": [{"given": "", "p": [0.5, 0.5]}]},
+		            {"name": "B", "parents": ["A"], "cpt": [{"given": "TT", "p": [1, 0]}, {"given": "FF", "p": [0, 1]}]}]}`,
+		// one row of the right length and one of the wrong length
+		`{"nodes": [{"name": "A", "cpt": [{"given": "", "p": [0.5, 0.5]}]},
+		            {"name": "B", "parents": ["A"], "cpt": [{"given": "T", "p": [1, 0]}, {"given": "FF", "p": [0, 1]}]}]}`,
+	}
+
+	for _, doc := range docs {
+		var bn BayesianNetwork
+		if err := json.NewDecoder(strings.NewReader(doc)).Decode(&bn); err == nil {
+			t.Errorf("expected an error for %s", doc)
+		}
+	}
+}
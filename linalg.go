@@ -0,0 +1,136 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+)
+
+// Small dense linear algebra used by the parameter fitting.
+// Matrices are stored row-major as [][]float64.
+
+// solves a x = b by Gaussian elimination with partial pivoting
+// - a and b are not modified
+func solveLinear(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n+1)
+		copy(m[i], a[i])
+		m[i][n] = b[i]
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-300 {
+			return nil, fmt.Errorf("singular matrix")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+
+		for row := col + 1; row < n; row++ {
+			f := m[row][col] / m[col][col]
+			if f == 0 {
+				continue
+			}
+			for k := col; k <= n; k++ {
+				m[row][k] -= f * m[col][k]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		sum := m[i][n]
+		for k := i + 1; k < n; k++ {
+			sum -= m[i][k] * x[k]
+		}
+		x[i] = sum / m[i][i]
+	}
+	return x, nil
+}
+
+func newMatrix(rows, cols int) [][]float64 {
+	m := make([][]float64, rows)
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+	return m
+}
+
+// the Cholesky factor L of a symmetric positive definite
+// matrix a = L L', lower triangular
+func cholesky(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	l := newMatrix(n, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			sum := a[i][j]
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if sum <= 0 {
+					return nil, fmt.Errorf("matrix is not positive definite")
+				}
+				l[i][i] = math.Sqrt(sum)
+			} else {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+	return l, nil
+}
+
+// the inverse of a symmetric positive definite matrix,
+// along with the log of its determinant
+func invertSPD(a [][]float64) ([][]float64, float64, error) {
+	n := len(a)
+	l, err := cholesky(a)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	logDet := 0.0
+	for i := 0; i < n; i++ {
+		logDet += 2 * math.Log(l[i][i])
+	}
+
+	// solves L L' x = e_j for every column j
+	inv := newMatrix(n, n)
+	y := make([]float64, n)
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			sum := 0.0
+			if i == j {
+				sum = 1
+			}
+			for k := 0; k < i; k++ {
+				sum -= l[i][k] * y[k]
+			}
+			y[i] = sum / l[i][i]
+		}
+		for i := n - 1; i >= 0; i-- {
+			sum := y[i]
+			for k := i + 1; k < n; k++ {
+				sum -= l[k][i] * inv[k][j]
+			}
+			inv[i][j] = sum / l[i][i]
+		}
+	}
+	return inv, logDet, nil
+}
+
+// the product of the matrix and the vector
+func multiply(a [][]float64, x []float64) []float64 {
+	y := make([]float64, len(a))
+	for i, row := range a {
+		for j, v := range row {
+			y[i] += v * x[j]
+		}
+	}
+	return y
+}
@@ -0,0 +1,401 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Logistic and softmax CPDs: the log-odds of the node are a linear
+// function of the parents, with one weight per parent instead of one
+// parameter per CPT row. The value of a parent is the feature
+// x_i = 1 for "T" and x_i = 0 for "F".
+
+// The logistic regression CPD
+//
+//	P(node=T | parents) = sigmoid(Bias + sum_i Weights[i] * x_i)
+type LogisticCPD struct {
+	Bias    float64
+	Weights []float64
+}
+
+// Generate a node with a logistic regression CPD, one weight per parent
+func NewLogisticNode(name string, parents []string, bias float64, weights []float64) *Node {
+	return NewCPDNode(name, parents, &LogisticCPD{Bias: bias, Weights: weights})
+}
+
+func (l *LogisticCPD) Probability(key string) float64 {
+	return sigmoid(linear(l.Bias, l.Weights, key))
+}
+
+func (l *LogisticCPD) NumParameters() int {
+	return len(l.Weights) + 1
+}
+
+func (l *LogisticCPD) Validate(numParents int) error {
+	if len(l.Weights) != numParents {
+		return fmt.Errorf("logistic CPD has %d weights for %d parents", len(l.Weights), numParents)
+	}
+	return finite(append([]float64{l.Bias}, l.Weights...))
+}
+
+// The softmax model of a graded child with the levels 0..levels-1,
+// encoded by the nodes of GradeNames
+//
+//	P(child = y | parents) = exp(s_y) / sum_k exp(s_k)
+//	s_y = bias[y-1] + sum_i weights[y-1][i] * x_i
+//
+// where level 0 is the reference with s_0 = 0
+type softmax struct {
+	levels  int
+	bias    []float64
+	weights [][]float64
+}
+
+// the binary node "child>=level" of a softmax model, with the same
+// parents as the grades of noisy-MAX, see NewNoisyMAXNodes
+type softmaxGrade struct {
+	model *softmax
+	level int
+}
+
+// Generate the binary nodes that encode a graded child with the given
+// number of levels under the softmax model, see GradeNames. bias and
+// weights hold one entry per level above 0, with one weight per parent.
+// With two levels this is the logistic model
+func NewSoftmaxNodes(name string, levels int, parents []string, bias []float64, weights [][]float64) BayNodes {
+	model := &softmax{
+		levels:  levels,
+		bias:    bias,
+		weights: weights,
+	}
+
+	grades := GradeNames(name, levels)
+	nodes := make(BayNodes, 0, len(grades))
+	for j, grade := range grades {
+		var nodeParents []string
+		if j+1 < len(grades) {
+			nodeParents = append(nodeParents, grades[j+1])
+		}
+		nodeParents = append(nodeParents, parents...)
+		nodes = append(nodes, NewCPDNode(grade, nodeParents, &softmaxGrade{model: model, level: j + 1}))
+	}
+	return nodes
+}
+
+func (g *softmaxGrade) Probability(key string) float64 {
+	return gradeProbability(g.level, g.model.levels, key, func(y int, parents string) float64 {
+		dist := g.model.distribution(parents)
+		p := 0.0
+		for k := 0; k <= y; k++ {
+			p += dist[k]
+		}
+		return p
+	})
+}
+
+// P(child = y | parents) for every level y
+func (m *softmax) distribution(key string) []float64 {
+	scores := make([]float64, m.levels)
+	for y := 1; y < m.levels; y++ {
+		scores[y] = linear(m.bias[y-1], m.weights[y-1], key)
+	}
+	z := logSumExp(scores...)
+	for y := range scores {
+		scores[y] = math.Exp(scores[y] - z)
+	}
+	return scores
+}
+
+// the parameters of the model are counted once, on the highest level
+func (g *softmaxGrade) NumParameters() int {
+	if g.level != g.model.levels-1 {
+		return 0
+	}
+	k := 0
+	for _, w := range g.model.weights {
+		k += len(w) + 1
+	}
+	return k
+}
+
+func (g *softmaxGrade) Validate(numParents int) error {
+	m := g.model
+	if m.levels < 2 {
+		return fmt.Errorf("softmax needs at least 2 levels, has %d", m.levels)
+	}
+	if len(m.bias) != m.levels-1 || len(m.weights) != m.levels-1 {
+		return fmt.Errorf("softmax needs a bias and weights for each of the %d levels above 0", m.levels-1)
+	}
+	if g.level < m.levels-1 {
+		numParents--
+	}
+	for _, w := range m.weights {
+		if len(w) != numParents {
+			return fmt.Errorf("softmax has %d weights for %d parents", len(w), numParents)
+		}
+		if err := finite(w); err != nil {
+			return err
+		}
+	}
+	return finite(m.bias)
+}
+
+// bias + sum_i weights[i] * x_i for the parent values of the key
+func linear(bias float64, weights []float64, key string) float64 {
+	s := bias
+	for i := 0; i < len(key); i++ {
+		if key[i] == 'T' {
+			s += weights[i]
+		}
+	}
+	return s
+}
+
+func sigmoid(s float64) float64 {
+	return 1 / (1 + math.Exp(-s))
+}
+
+func finite(values []float64) error {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("parameter %f is not finite", v)
+		}
+	}
+	return nil
+}
+
+// Options of the regularized maximum likelihood fit of logistic and
+// softmax CPDs, the zero value of a field selects its default
+type LogisticOptions struct {
+	// L2 penalty on the weights, default 1. The bias only has a tiny
+	// penalty that keeps it finite when a level never occurs
+	Lambda float64
+	// maximum number of Newton steps, default 100
+	Iterations int
+	// stops once no parameter changes by more than the tolerance,
+	// default 1e-9
+	Tolerance float64
+}
+
+// penalty on the bias
+const biasPenalty = 1e-6
+
+func (opts *LogisticOptions) withDefaults() (LogisticOptions, error) {
+	o := LogisticOptions{
+		Lambda:     1,
+		Iterations: 100,
+		Tolerance:  1e-9,
+	}
+	if opts == nil {
+		return o, nil
+	}
+	if opts.Lambda < 0 || opts.Iterations < 0 || opts.Tolerance < 0 {
+		return o, fmt.Errorf("invalid logistic options: %+v", *opts)
+	}
+	if opts.Lambda > 0 {
+		o.Lambda = opts.Lambda
+	}
+	if opts.Iterations > 0 {
+		o.Iterations = opts.Iterations
+	}
+	if opts.Tolerance > 0 {
+		o.Tolerance = opts.Tolerance
+	}
+	return o, nil
+}
+
+// Fits a logistic regression CPD of the node on its parents by
+// maximizing the L2-penalized log-likelihood of the dataset, opts may
+// be nil
+// - rows with a missing value of the node or a parent are skipped
+func FitLogisticNode(ds *Dataset, name string, parents []string, opts *LogisticOptions) (*Node, error) {
+	theta, err := fitGraded(ds, []string{name}, parents, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogisticNode(name, parents, theta[0][0], theta[0][1:]), nil
+}
+
+// Fits the softmax model of a graded child on its parents, see
+// FitLogisticNode. The dataset holds the columns of GradeNames(name, levels)
+func FitSoftmaxNodes(ds *Dataset, name string, levels int, parents []string, opts *LogisticOptions) (BayNodes, error) {
+	if levels < 2 {
+		return nil, fmt.Errorf("softmax needs at least 2 levels, has %d", levels)
+	}
+	theta, err := fitGraded(ds, GradeNames(name, levels), parents, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	bias := make([]float64, levels-1)
+	weights := make([][]float64, levels-1)
+	for y := range theta {
+		bias[y], weights[y] = theta[y][0], theta[y][1:]
+	}
+	return NewSoftmaxNodes(name, levels, parents, bias, weights), nil
+}
+
+// the rows of the dataset with the same assignment of the parents,
+// and their number at every level of the child
+type levelCounts struct {
+	key    string
+	counts []float64
+}
+
+// fits the softmax model of the child encoded by the grade columns,
+// returning theta[y-1] = bias, weights... of every level y above 0
+func fitGraded(ds *Dataset, grades, parents []string, opts *LogisticOptions) ([][]float64, error) {
+	o, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range append(append([]string{}, grades...), parents...) {
+		if _, ok := ds.index[column]; !ok {
+			return nil, fmt.Errorf("column '%s' is not in the dataset", column)
+		}
+	}
+
+	levels := len(grades) + 1
+	index := make(map[string]int)
+	var rows []levelCounts
+	for i := 0; i < ds.Len(); i++ {
+		level, ok, err := gradeLevel(ds, i, grades)
+		if err != nil {
+			return nil, err
+		}
+		var key strings.Builder
+		for _, parent := range parents {
+			v := ds.Value(i, parent)
+			if v == "" {
+				ok = false
+			}
+			key.WriteString(v)
+		}
+		if !ok {
+			continue
+		}
+
+		j, seen := index[key.String()]
+		if !seen {
+			j = len(rows)
+			index[key.String()] = j
+			rows = append(rows, levelCounts{key: key.String(), counts: make([]float64, levels)})
+		}
+		rows[j].counts[level]++
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no complete rows to fit %v", grades)
+	}
+	return fitSoftmax(rows, levels, len(parents), o)
+}
+
+// the level of the graded child in row i: the number of its grades
+// that are T, which must be the lowest grades
+// - reports false if a grade is missing
+func gradeLevel(ds *Dataset, i int, grades []string) (int, bool, error) {
+	level := 0
+	for j, grade := range grades {
+		switch ds.Value(i, grade) {
+		case "":
+			return 0, false, nil
+		case "T":
+			if level != j {
+				return 0, false, fmt.Errorf("row %d: %s is T while a lower grade is F", i, grade)
+			}
+			level++
+		}
+	}
+	return level, true, nil
+}
+
+// Newton's method on the penalized log-likelihood of the softmax model
+// with level 0 as the reference. The parameters of level y are at
+// (y-1)*d..y*d-1 of theta, with d = numParents+1 and the bias first
+func fitSoftmax(rows []levelCounts, levels, numParents int, opts LogisticOptions) ([][]float64, error) {
+	d := numParents + 1
+	n := (levels - 1) * d
+	theta := make([]float64, n)
+
+	// the features of every row, 1 for the bias
+	features := make([][]float64, len(rows))
+	for r, row := range rows {
+		x := make([]float64, d)
+		x[0] = 1
+		for i := 0; i < numParents; i++ {
+			if row.key[i] == 'T' {
+				x[i+1] = 1
+			}
+		}
+		features[r] = x
+	}
+
+	for iteration := 0; iteration < opts.Iterations; iteration++ {
+		gradient := make([]float64, n)
+		hessian := newMatrix(n, n)
+		for r, row := range rows {
+			x := features[r]
+			scores := make([]float64, levels)
+			for y := 1; y < levels; y++ {
+				for f := 0; f < d; f++ {
+					scores[y] += theta[(y-1)*d+f] * x[f]
+				}
+			}
+			z := logSumExp(scores...)
+			total := 0.0
+			for _, c := range row.counts {
+				total += c
+			}
+
+			p := make([]float64, levels)
+			for y := range p {
+				p[y] = math.Exp(scores[y] - z)
+			}
+			for y := 1; y < levels; y++ {
+				for f := 0; f < d; f++ {
+					gradient[(y-1)*d+f] += (row.counts[y] - total*p[y]) * x[f]
+				}
+				for k := 1; k < levels; k++ {
+					w := -total * p[y] * p[k]
+					if k == y {
+						w += total * p[y]
+					}
+					for f := 0; f < d; f++ {
+						for g := 0; g < d; g++ {
+							hessian[(y-1)*d+f][(k-1)*d+g] += w * x[f] * x[g]
+						}
+					}
+				}
+			}
+		}
+
+		// the penalty, hessian holds the negated second derivatives
+		for i := range theta {
+			penalty := opts.Lambda
+			if i%d == 0 {
+				penalty = biasPenalty
+			}
+			gradient[i] -= penalty * theta[i]
+			hessian[i][i] += penalty
+		}
+
+		step, err := solveLinear(hessian, gradient)
+		if err != nil {
+			return nil, err
+		}
+		change := 0.0
+		for i := range theta {
+			theta[i] += step[i]
+			change = math.Max(change, math.Abs(step[i]))
+		}
+		if change < opts.Tolerance {
+			break
+		}
+	}
+
+	result := make([][]float64, levels-1)
+	for y := range result {
+		result[y] = theta[y*d : (y+1)*d]
+	}
+	return result, nil
+}
@@ -0,0 +1,122 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+// three risk factors of a disease with a logistic CPD
+func BuildRiskNetwork() *BayesianNetwork {
+	return NewBayesianNetwork(
+		NewRootNode("Smoker", 0.3),
+		NewRootNode("Old", 0.4),
+		NewRootNode("Obese", 0.2),
+		NewLogisticNode("Disease", []string{"Smoker", "Old", "Obese"}, -2, []float64{1.5, 1, -0.5}),
+	)
+}
+
+func TestLogisticCPD(t *testing.T) {
+	bn := BuildRiskNetwork()
+	disease := bn.nodes["Disease"]
+	if p, exp := disease.prob("TTF"), sigmoid(0.5); math.Abs(p-exp) > 1e-12 {
+		t.Errorf("P(Disease|TTF): Exp %.4f != %.4f Act", exp, p)
+	}
+	if k := bn.NumParameters(); k != 3+4 {
+		t.Errorf("Exp 7 != %d Act parameters", k)
+	}
+	if err := NewLogisticNode("X", []string{"A"}, 0, nil).validateCPT(1); err == nil {
+		t.Errorf("Expected an error for a missing weight")
+	}
+
+	// the samplers read the CPD through P()
+	Seed(42)
+	exp, err := bn.ExactInference(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareStatMaps(exp, bn.AncestralSampling(10000), t)
+}
+
+func TestFitLogisticNode(t *testing.T) {
+	Seed(42)
+	ds := BuildRiskNetwork().SampleDataset(20000)
+
+	node, err := FitLogisticNode(ds, "Disease", []string{"Smoker", "Old", "Obese"}, &LogisticOptions{Lambda: 1e-6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := node.GetCPD().(*LogisticCPD)
+	exp := []float64{1.5, 1, -0.5}
+	if math.Abs(l.Bias+2) > 0.1 {
+		t.Errorf("bias: Exp -2 != %.4f Act", l.Bias)
+	}
+	for i, w := range l.Weights {
+		if math.Abs(w-exp[i]) > 0.15 {
+			t.Errorf("weight %d: Exp %.4f != %.4f Act", i, exp[i], w)
+		}
+	}
+
+	// a strong penalty shrinks the weights towards 0
+	shrunk, err := FitLogisticNode(ds, "Disease", []string{"Smoker", "Old", "Obese"}, &LogisticOptions{Lambda: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, w := range shrunk.GetCPD().(*LogisticCPD).Weights {
+		if math.Abs(w) >= math.Abs(l.Weights[i]) {
+			t.Errorf("weight %d: Exp |%.4f| < |%.4f| Act", i, w, l.Weights[i])
+		}
+	}
+
+	if _, err := FitLogisticNode(ds, "Disease", []string{"Missing"}, nil); err == nil {
+		t.Errorf("Expected an error for a missing column")
+	}
+}
+
+func TestSoftmaxNodes(t *testing.T) {
+	// two levels are the logistic model
+	nodes := NewSoftmaxNodes("Y", 2, []string{"A", "B"}, []float64{-1}, [][]float64{{2, 0.5}})
+	logistic := NewLogisticNode("Y", []string{"A", "B"}, -1, []float64{2, 0.5})
+	for _, key := range cptKeys(2) {
+		if math.Abs(nodes[0].prob(key)-logistic.prob(key)) > 1e-12 {
+			t.Errorf("%s: Exp %.4f != %.4f Act", key, logistic.prob(key), nodes[0].prob(key))
+		}
+	}
+
+	// a child with three levels, sampled and fitted
+	bias := []float64{0.5, -1}
+	weights := [][]float64{{-1, 1}, {2, 0.5}}
+	model := &softmax{levels: 3, bias: bias, weights: weights}
+	bn := NewBayesianNetwork(append(BayNodes{
+		NewRootNode("A", 0.5),
+		NewRootNode("B", 0.4),
+	}, NewSoftmaxNodes("Y", 3, []string{"A", "B"}, bias, weights)...)...)
+
+	// the marginal of the grades given the parents
+	stats, err := bn.ExactInference(map[string]string{"A": "T", "B": "F"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dist := model.distribution("TF")
+	if exp := dist[1] + dist[2]; math.Abs(stats["Y>=1"][0]-exp) > 1e-9 {
+		t.Errorf("P(Y>=1|TF): Exp %.6f != %.6f Act", exp, stats["Y>=1"][0])
+	}
+	if math.Abs(stats["Y>=2"][0]-dist[2]) > 1e-9 {
+		t.Errorf("P(Y>=2|TF): Exp %.6f != %.6f Act", dist[2], stats["Y>=2"][0])
+	}
+
+	Seed(42)
+	ds := bn.SampleDataset(20000)
+	fitted, err := FitSoftmaxNodes(ds, "Y", 3, []string{"A", "B"}, &LogisticOptions{Lambda: 1e-6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fit := fitted[0].GetCPD().(*softmaxGrade).model
+	for _, key := range cptKeys(2) {
+		exp, act := model.distribution(key), fit.distribution(key)
+		for y := range exp {
+			if math.Abs(exp[y]-act[y]) > 0.02 {
+				t.Errorf("P(Y=%d|%s): Exp %.4f != %.4f Act", y, key, exp[y], act[y])
+			}
+		}
+	}
+}
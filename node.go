@@ -5,15 +5,47 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"strconv"
+	"sync"
 	"time"
 )
 
-func init() {
-	rand.Seed(time.Now().UTC().UnixNano())
+// the source of every sampler, seeded from the clock. rand.Seed
+// does not seed the global source of math/rand, see Seed
+var rng = rand.New(&lockedSource{src: rand.NewSource(time.Now().UTC().UnixNano()).(rand.Source64)})
+
+// Seeds the samplers, so that a run can be repeated
+func Seed(seed int64) {
+	rng.Seed(seed)
+}
+
+// a source that is safe for concurrent use, like
+// the global source of math/rand
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
 }
 
 type Node struct {
-	// id of a parent node must be larger than 
+	// id of a parent node must be smaller than 
 	// id of every one of their childnodes
 	id int
 	// name of the random variable
@@ -38,6 +70,21 @@ type Node struct {
 	// - the value returned in a CPT lookup
 	//   is always the "T" value.
 	cpt map[string]float64
+	// compact distribution used instead of the CPT,
+	// nil for nodes with a table
+	cpd CPD
+	// distributions of a continuous node keyed by the states of
+	// its binary parents, "" without binary parents.
+	// nil for binary nodes
+	gaussians map[string]*LinearGaussian
+	// value of a continuous node, valid when assignment != ""
+	value float64
+	// outcome names of the "T" and "F" states
+	// used when exchanging the network with
+	// other tools. Empty <=> "T"/"F"
+	states [2]string
+	// free-form metadata on the node
+	properties map[string]string
 	// key strisdfdskklloiuygfdsasdfghjkng
 	// after a node has been sampled
 	// this will contain the 
@@ -107,11 +154,55 @@ func (self *Node) computeKey() string {
 	return buffer.String()
 }
 
+// returns every CPT key for n parents, with the
+// first parent varying slowest and "T" before "F":
+// n = 2 => [TT TF FT FF]
+func cptKeys(n int) []string {
+	keys := make([]string, 0, 1<<uint(n))
+	key := make([]byte, n)
+	for i := 0; i < 1<<uint(n); i++ {
+		for j := 0; j < n; j++ {
+			if i&(1<<uint(n-1-j)) == 0 {
+				key[j] = 'T'
+			} else {
+				key[j] = 'F'
+			}
+		}
+		keys = append(keys, string(key))
+	}
+	return keys
+}
+
+// returns the keys of the CPT rows in cptKeys order
+// - a root node has a single row keyed "T"
+func (self *Node) rowKeys() []string {
+	if len(self.parentNames) == 0 {
+		return []string{"T"}
+	}
+	return cptKeys(len(self.parentNames))
+}
+
+// returns P(node=T | parents=key) of a CPT row, expanding
+// the row from the CPD if the node has one
+func (self *Node) prob(key string) float64 {
+	if self.cpd == nil {
+		return self.cpt[key]
+	}
+	if len(self.parentNames) == 0 {
+		return self.cpd.Probability("")
+	}
+	return self.cpd.Probability(key)
+}
+
 // Generate the CPT lookup-key from parent assignment variables
 // - if this has already been generated, returned cached value
 func (self *Node) CPT() float64 {
 	key := self.computeKey()
 
+	if self.cpd != nil {
+		return self.prob(key)
+	}
+
 	if prob, ok := self.cpt[key]; ok == true {
 		return prob
 	}
@@ -144,7 +235,7 @@ func (self *Node) Sample() string {
 	cptProb := self.CPT()
 
 	// generate random float64 for sampling
-	random := rand.Float64()
+	random := rng.Float64()
 
 	if random > cptProb {
 		return "F"
@@ -180,6 +271,86 @@ func (self *Node) Name() string {
 	return self.name
 }
 
+// returns an unlinked copy of the node, ready to be
+// added to another network
+// - the linear Gaussians and a logistic CPD are copied, every other
+//   CPD is immutable (see CPD) and shared with the copy
+func (self *Node) clone() *Node {
+	cpt := make(map[string]float64, len(self.cpt))
+	for k, v := range self.cpt {
+		cpt[k] = v
+	}
+
+	var properties map[string]string
+	if self.properties != nil {
+		properties = make(map[string]string, len(self.properties))
+		for k, v := range self.properties {
+			properties[k] = v
+		}
+	}
+
+	var gaussians map[string]*LinearGaussian
+	if self.gaussians != nil {
+		gaussians = make(map[string]*LinearGaussian, len(self.gaussians))
+		for k, lg := range self.gaussians {
+			gaussians[k] = &LinearGaussian{
+				Intercept: lg.Intercept,
+				Weights:   append([]float64(nil), lg.Weights...),
+				Variance:  lg.Variance,
+			}
+		}
+	}
+
+	cpd := self.cpd
+	if l, ok := cpd.(*LogisticCPD); ok {
+		cpd = &LogisticCPD{Bias: l.Bias, Weights: append([]float64(nil), l.Weights...)}
+	}
+
+	node := &Node{
+		name:        self.name,
+		parentNames: append([]string(nil), self.parentNames...),
+		parentIds:   make([]*Node, 0, len(self.parentNames)),
+		childIds:    make([]*Node, 0, 4),
+		cpt:         cpt,
+		cpd:         cpd,
+		gaussians:   gaussians,
+		states:      self.states,
+		properties:  properties,
+	}
+	return node
+}
+
+// returns the outcome names of the node, the name
+// of the "T" state first and the "F" state second
+func (self *Node) States() []string {
+	if self.states[0] == "" {
+		return []string{"T", "F"}
+	}
+	return []string{self.states[0], self.states[1]}
+}
+
+// names the "T" and "F" outcomes of the node
+func (self *Node) SetStates(t, f string) {
+	self.states = [2]string{t, f}
+}
+
+// returns the metadata stored under key, or "" if not set
+func (self *Node) Property(key string) string {
+	return self.properties[key]
+}
+
+func (self *Node) SetProperty(key, value string) {
+	if self.properties == nil {
+		self.properties = make(map[string]string)
+	}
+	self.properties[key] = value
+}
+
+// returns all the metadata of the node
+func (self *Node) Properties() map[string]string {
+	return self.properties
+}
+
 func (self *Node) Id() int {
 	return self.id
 }
@@ -272,14 +443,54 @@ func (self *Node) IsRoot() bool {
 
 func (self *Node) SetAssignment(value string) {
 	self.assignment = value
+	if self.gaussians != nil {
+		// the value of a continuous node, 0 if it is not a number
+		self.value, _ = strconv.ParseFloat(value, 64)
+	}
 	// for _, child := range self.childIds {
 	// 	child.ResetKey()
 	// }
 }
 
+// validates an observed value of the node: a state of a
+// binary node, a finite number of a continuous node
+func (self *Node) checkAssignment(value string) error {
+	if self.gaussians == nil {
+		_, err := stateBit(value)
+		return err
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("Invalid value: '%s' should be a finite number", value)
+	}
+	return nil
+}
+
 func (self *Node) ValidateCPT() error {
+	return self.validateCPT(self.NumParents())
+}
+
+// validates the CPT against the given number of parents
+// - used directly by the decoders before the node
+//   has been linked to its parents
+func (self *Node) validateCPT(numParents int) error {
+	if self.gaussians != nil {
+		for key, lg := range self.gaussians {
+			if err := lg.validate(numParents - len(key)); err != nil {
+				return fmt.Errorf("%s: %v", self.name, err)
+			}
+		}
+		return nil
+	}
+	if self.cpd != nil {
+		if err := self.cpd.Validate(numParents); err != nil {
+			return fmt.Errorf("%s: %v", self.name, err)
+		}
+		return nil
+	}
+
 	// root node
-	if self.IsRoot() {
+	if numParents == 0 {
 		if len(self.cpt) != 2 {
 			return fmt.Errorf("(Root): %s's CPT has wrong dimension: %d != %d act (cpt: %v)",
 				self.name, 2, len(self.cpt), self.cpt)
@@ -288,14 +499,14 @@ func (self *Node) ValidateCPT() error {
 	}
 
 	for k, _ := range self.cpt {
-		if len(k) != self.NumParents() {
+		if len(k) != numParents {
 			return fmt.Errorf("%s's CPT has wrong key-length: exp: %d != %d act (cpt: %v)",
-				self.name, self.NumParents(), len(k), self.cpt)
+				self.name, numParents, len(k), self.cpt)
 		}
 		break
 	}
 
-	exptectedCPTSize := int(math.Pow(2, float64(self.NumParents())))
+	exptectedCPTSize := int(math.Pow(2, float64(numParents)))
 	// fmt.Printf("%s: exp: %v\n", self.name, exptectedCPTSize)
 	if len(self.cpt) != exptectedCPTSize {
 		return fmt.Errorf("%s's CPT has wrong dimensions: exp: %d != %d act (cpt: %v)",
@@ -323,6 +534,15 @@ func (bn BayNodes) Less(i, j int) bool {
 	return bn[i].Id() < bn[j].Id()
 }
 
+// the names of the nodes, in their order
+func nodeNames(nodes BayNodes) []string {
+	n := make([]string, len(nodes))
+	for i, node := range nodes {
+		n[i] = node.Name()
+	}
+	return n
+}
+
 func (self BayNodes) String() string {
 
 	if len(self) == 0 {
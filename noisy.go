@@ -0,0 +1,276 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+)
+
+// Noisy-OR and noisy-MAX distributions: every parent is an
+// independent cause of the node, described by one parameter per
+// parent instead of one per CPT row.
+
+// The noisy-OR distribution:
+//
+//	P(node=F | parents) = (1-leak) * prod over the parents that are T of (1-links[i])
+//
+// links[i] is the probability that parent i alone makes the node T,
+// the leak the probability that the node is T without any cause
+type noisyOR struct {
+	links []float64
+	leak  float64
+}
+
+// Generate a noisy-OR node with one link probability per parent
+// and a leak probability
+func NewNoisyORNode(name string, parents []string, links []float64, leak float64) *Node {
+	return NewCPDNode(name, parents, &noisyOR{
+		links: links,
+		leak:  leak,
+	})
+}
+
+func (n *noisyOR) Probability(key string) float64 {
+	pF := 1 - n.leak
+	for i := 0; i < len(key); i++ {
+		if key[i] == 'T' {
+			pF *= 1 - n.links[i]
+		}
+	}
+	return 1 - pF
+}
+
+func (n *noisyOR) NumParameters() int {
+	return len(n.links) + 1
+}
+
+func (n *noisyOR) Validate(numParents int) error {
+	if len(n.links) != numParents {
+		return fmt.Errorf("noisy-OR has %d links for %d parents", len(n.links), numParents)
+	}
+	for _, p := range append([]float64{n.leak}, n.links...) {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("noisy-OR probability %f is not in [0, 1]", p)
+		}
+	}
+	return nil
+}
+
+// name of the auxiliary variable of the decomposition of a node
+func auxiliaryName(name string) string {
+	return "\x00" + name
+}
+
+// The multiplicative decomposition of a noisy-OR node (Díez & Galán):
+// with an auxiliary binary variable a,
+//
+//	P(y | x) = sum_a h(a, y) prod_i h_i(x_i, a)
+//
+// where h(T, y) = [y = T], h(F, y) = (1-leak) * (-1 if y = T else 1),
+// h_i(x_i, T) = 1 and h_i(x_i, F) = 1-links[i] if x_i = T else 1.
+// This replaces the table of 2^(n+1) entries by n+1 small factors
+// - some entries are negative, only valid for sum-product elimination
+func (n *noisyOR) factors(node *Node) []*factor {
+	a := auxiliaryName(node.Name())
+
+	h := newFactor([]string{a, node.Name()})
+	h.values = []float64{1, 0, -(1 - n.leak), 1 - n.leak}
+
+	factors := []*factor{h}
+	for i, parent := range node.parentNames {
+		hi := newFactor([]string{parent, a})
+		hi.values = []float64{1, 1 - n.links[i], 1, 1}
+		factors = append(factors, hi)
+	}
+	return factors
+}
+
+// A graded parent of a noisy-MAX node: a variable with the levels
+// 0..Levels-1, where level 0 is the normal level without any effect.
+// Effects[l-1][y] is the probability that the child is at level y
+// when the parent is at level l and every other cause is absent
+type GradedParent struct {
+	Name    string
+	Levels  int
+	Effects [][]float64
+}
+
+// Name of the binary node "name>=level" that encodes a graded variable
+func GradeName(name string, level int) string {
+	return fmt.Sprintf("%s>=%d", name, level)
+}
+
+// The names of the binary nodes that encode a graded variable: one
+// node GradeName(name, j) per level j > 0. A variable with two levels
+// is the binary node itself, with T as level 1
+func GradeNames(name string, levels int) []string {
+	if levels == 2 {
+		return []string{name}
+	}
+	grades := make([]string, 0, levels-1)
+	for j := 1; j < levels; j++ {
+		grades = append(grades, GradeName(name, j))
+	}
+	return grades
+}
+
+// The noisy-MAX model of a graded child: the level of the child is the
+// maximum of the levels caused by every parent and the leak, so its
+// cumulative distribution is the product of theirs
+//
+//	P(child <= y | parents) = Leak(<= y) * prod_i Effect_i(<= y | level of parent i)
+type noisyMAX struct {
+	levels  int
+	leak    []float64
+	parents []GradedParent
+}
+
+// the binary node "child>=level" of a noisy-MAX model. Its parents
+// are the node "child>=level+1", unless it is the highest level,
+// followed by the grades of every parent
+type noisyMAXGrade struct {
+	model *noisyMAX
+	level int
+}
+
+// Generate the binary nodes that encode a graded child with the given
+// number of levels under the noisy-MAX model, see GradeNames.
+// leak[y] is the probability that the child is at level y when every
+// parent is at level 0. With two levels and binary parents, this is
+// the noisy-OR model
+func NewNoisyMAXNodes(name string, levels int, leak []float64, parents []GradedParent) BayNodes {
+	model := &noisyMAX{
+		levels:  levels,
+		leak:    leak,
+		parents: parents,
+	}
+
+	causes := make([]string, 0, len(parents))
+	for _, parent := range parents {
+		causes = append(causes, GradeNames(parent.Name, parent.Levels)...)
+	}
+
+	grades := GradeNames(name, levels)
+	nodes := make(BayNodes, 0, len(grades))
+	for j, grade := range grades {
+		var nodeParents []string
+		if j+1 < len(grades) {
+			nodeParents = append(nodeParents, grades[j+1])
+		}
+		nodeParents = append(nodeParents, causes...)
+		nodes = append(nodes, NewCPDNode(grade, nodeParents, &noisyMAXGrade{model: model, level: j + 1}))
+	}
+	return nodes
+}
+
+func (g *noisyMAXGrade) Probability(key string) float64 {
+	return gradeProbability(g.level, g.model.levels, key, func(y int, parents string) float64 {
+		// the level of every parent is its number of grades that are T
+		levels := make([]int, len(g.model.parents))
+		pos := 0
+		for i, parent := range g.model.parents {
+			for j := 1; j < parent.Levels; j++ {
+				if parents[pos] == 'T' {
+					levels[i]++
+				}
+				pos++
+			}
+		}
+		return g.model.cumulative(y, levels)
+	})
+}
+
+// P(child>=level | child>=level+1, parents) of the node
+// "child>=level" of a graded child, see GradeNames, which is 1 if
+// the child is at least at the next level, and otherwise
+// P(child = level | parents) / P(child <= level | parents)
+// - cumulative returns P(child <= y | parents) for the key of the
+//   parents without the node of the next level
+func gradeProbability(level, levels int, key string, cumulative func(y int, parents string) float64) float64 {
+	if level < levels-1 {
+		if key[0] == 'T' {
+			return 1
+		}
+		key = key[1:]
+	}
+
+	below := cumulative(level-1, key)
+	if level == levels-1 {
+		return 1 - below
+	}
+	at := cumulative(level, key)
+	if at == 0 {
+		return 0
+	}
+	return (at - below) / at
+}
+
+// P(child <= y | the parents are at the given levels)
+func (m *noisyMAX) cumulative(y int, levels []int) float64 {
+	p := 0.0
+	for k := 0; k <= y; k++ {
+		p += m.leak[k]
+	}
+	for i, parent := range m.parents {
+		if levels[i] == 0 {
+			continue
+		}
+		effect := 0.0
+		for k := 0; k <= y; k++ {
+			effect += parent.Effects[levels[i]-1][k]
+		}
+		p *= effect
+	}
+	return p
+}
+
+// the parameters of the model are counted once, on the highest level
+func (g *noisyMAXGrade) NumParameters() int {
+	if g.level != g.model.levels-1 {
+		return 0
+	}
+	k := g.model.levels - 1
+	for _, parent := range g.model.parents {
+		k += (parent.Levels - 1) * (g.model.levels - 1)
+	}
+	return k
+}
+
+func (g *noisyMAXGrade) Validate(numParents int) error {
+	m := g.model
+	if m.levels < 2 {
+		return fmt.Errorf("noisy-MAX needs at least 2 levels, has %d", m.levels)
+	}
+
+	expected := 0
+	if g.level < m.levels-1 {
+		expected++
+	}
+	distributions := [][]float64{m.leak}
+	for _, parent := range m.parents {
+		if parent.Levels < 2 || len(parent.Effects) != parent.Levels-1 {
+			return fmt.Errorf("graded parent '%s' has %d effects for %d levels", parent.Name, len(parent.Effects), parent.Levels)
+		}
+		expected += parent.Levels - 1
+		distributions = append(distributions, parent.Effects...)
+	}
+	if numParents != expected {
+		return fmt.Errorf("noisy-MAX grade has %d parents, expected %d", numParents, expected)
+	}
+
+	for _, dist := range distributions {
+		if len(dist) != m.levels {
+			return fmt.Errorf("noisy-MAX distribution %v does not have %d levels", dist, m.levels)
+		}
+		sum := 0.0
+		for _, p := range dist {
+			if p < 0 {
+				return fmt.Errorf("noisy-MAX distribution %v has a negative probability", dist)
+			}
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			return fmt.Errorf("noisy-MAX distribution %v does not sum to 1", dist)
+		}
+	}
+	return nil
+}
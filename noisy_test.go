@@ -0,0 +1,163 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// three causes of a fever, with a noisy-OR fever and a symptom
+func BuildFeverNetwork() *BayesianNetwork {
+	return NewBayesianNetwork(
+		NewRootNode("Cold", 0.3),
+		NewRootNode("Flu", 0.1),
+		NewRootNode("Malaria", 0.01),
+		NewNoisyORNode("Fever", []string{"Cold", "Flu", "Malaria"}, []float64{0.4, 0.8, 0.9}, 0.05),
+		NewNode("Chills", []string{"Fever"}, map[string]float64{"T": 0.7, "F": 0.1}),
+	)
+}
+
+func TestNoisyOR(t *testing.T) {
+	bn := BuildFeverNetwork()
+	fever := bn.nodes["Fever"]
+
+	// only Flu: 1 - 0.95*0.2
+	if p := fever.prob("FTF"); math.Abs(p-0.81) > 1e-12 {
+		t.Errorf("P(Fever|Flu): Exp 0.81 != %.4f Act", p)
+	}
+	if p := fever.prob("FFF"); math.Abs(p-0.05) > 1e-12 {
+		t.Errorf("P(Fever|no cause): Exp 0.05 != %.4f Act", p)
+	}
+	if k := bn.NumParameters(); k != 3+4+2 {
+		t.Errorf("Exp 9 != %d Act parameters", k)
+	}
+
+	for _, evidence := range []map[string]string{
+		{},
+		{"Fever": "T"},
+		{"Chills": "T", "Cold": "F"},
+		{"Fever": "F", "Malaria": "T"},
+	} {
+		exp, z, _ := bruteForce(bn, evidence)
+		act, err := bn.ExactInference(evidence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, dist := range exp {
+			if math.Abs(dist[0]-act[name][0]) > 1e-9 {
+				t.Errorf("%v: %s: Exp %.6f != %.6f Act", evidence, name, dist[0], act[name][0])
+			}
+		}
+		logP, err := bn.LogEvidenceProbability(evidence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(logP-math.Log(z)) > 1e-9 {
+			t.Errorf("%v: log P(e): Exp %.6f != %.6f Act", evidence, math.Log(z), logP)
+		}
+	}
+
+	if err := NewNoisyORNode("X", []string{"A"}, []float64{0.5, 0.5}, 0).validateCPT(1); err == nil {
+		t.Errorf("Expected an error for a link without a parent")
+	}
+	if err := NewNoisyORNode("X", []string{"A"}, []float64{1.5}, 0).validateCPT(1); err == nil {
+		t.Errorf("Expected an error for a link above 1")
+	}
+}
+
+func TestNoisyORManyParents(t *testing.T) {
+	// a table of 2^30 rows could not be built
+	const n = 30
+	nodes := make(BayNodes, 0, n+1)
+	parents := make([]string, n)
+	links := make([]float64, n)
+	for i := range parents {
+		parents[i] = fmt.Sprintf("C%d", i)
+		links[i] = 0.1
+		nodes = append(nodes, NewRootNode(parents[i], 0.2))
+	}
+	nodes = append(nodes, NewNoisyORNode("Effect", parents, links, 0.01))
+	bn := NewBayesianNetwork(nodes...)
+
+	stats, err := bn.ExactInference(map[string]string{"Effect": "F"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the causes stay independent given Effect=F:
+	// P(C|E=F) = 0.2*0.9 / (0.2*0.9 + 0.8)
+	exp := 0.18 / 0.98
+	if math.Abs(stats["C7"][0]-exp) > 1e-9 {
+		t.Errorf("Exp %.6f != %.6f Act", exp, stats["C7"][0])
+	}
+
+	// P(Effect=F) = 0.99 * (1 - 0.2*0.1)^n
+	logP, err := bn.LogEvidenceProbability(map[string]string{"Effect": "F"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := math.Log(0.99) + n*math.Log(0.98); math.Abs(logP-exp) > 1e-9 {
+		t.Errorf("log P(Effect=F): Exp %.6f != %.6f Act", exp, logP)
+	}
+}
+
+func TestNoisyMAX(t *testing.T) {
+	// with two levels and binary parents noisy-MAX is noisy-OR
+	nodes := NewNoisyMAXNodes("Y", 2, []float64{0.95, 0.05}, []GradedParent{
+		{Name: "A", Levels: 2, Effects: [][]float64{{0.6, 0.4}}},
+		{Name: "B", Levels: 2, Effects: [][]float64{{0.2, 0.8}}},
+	})
+	or := NewNoisyORNode("Y", []string{"A", "B"}, []float64{0.4, 0.8}, 0.05)
+	if len(nodes) != 1 {
+		t.Fatalf("Exp 1 != %d Act nodes", len(nodes))
+	}
+	for _, key := range cptKeys(2) {
+		if math.Abs(nodes[0].prob(key)-or.prob(key)) > 1e-12 {
+			t.Errorf("%s: Exp %.4f != %.4f Act", key, or.prob(key), nodes[0].prob(key))
+		}
+	}
+
+	// a child with the levels 0, 1, 2 and a graded parent with 3 levels
+	leak := []float64{0.9, 0.08, 0.02}
+	effects := [][]float64{{0.5, 0.4, 0.1}, {0.1, 0.3, 0.6}}
+	bn := NewBayesianNetwork(append(BayNodes{
+		NewRootNode("P>=2", 0.2),
+		NewNode("P>=1", []string{"P>=2"}, map[string]float64{"T": 1, "F": 0.5}),
+	}, NewNoisyMAXNodes("Y", 3, leak, []GradedParent{
+		{Name: "P", Levels: 3, Effects: effects},
+	})...)...)
+
+	// P(P=0) = 0.4, P(P=1) = 0.4, P(P=2) = 0.2
+	levels := []float64{0.4, 0.4, 0.2}
+	var cumulative [3]float64
+	for y := range cumulative {
+		for l, p := range levels {
+			lc, ec := 0.0, 1.0
+			if l > 0 {
+				ec = 0
+			}
+			for k := 0; k <= y; k++ {
+				lc += leak[k]
+				if l > 0 {
+					ec += effects[l-1][k]
+				}
+			}
+			cumulative[y] += p * lc * ec
+		}
+	}
+
+	stats, err := bn.ExactInference(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// P(Y>=1) = 1 - P(Y<=0), P(Y>=2) = 1 - P(Y<=1)
+	if exp := 1 - cumulative[0]; math.Abs(stats["Y>=1"][0]-exp) > 1e-9 {
+		t.Errorf("P(Y>=1): Exp %.6f != %.6f Act", exp, stats["Y>=1"][0])
+	}
+	if exp := 1 - cumulative[1]; math.Abs(stats["Y>=2"][0]-exp) > 1e-9 {
+		t.Errorf("P(Y>=2): Exp %.6f != %.6f Act", exp, stats["Y>=2"][0])
+	}
+	// the parameters are counted once: 2 for the leak, 2*2 for P
+	if k := bn.NumParameters(); k != 3+6 {
+		t.Errorf("Exp 9 != %d Act parameters", k)
+	}
+}
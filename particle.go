@@ -0,0 +1,371 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Online inference over a dynamic network by particle filtering.
+// Every particle holds one assignment of the current slice; Step
+// advances all the particles by one slice given the evidence of
+// that step, without unrolling the network.
+
+// How the unobserved variables of a slice are sampled
+type Proposal int
+
+const (
+	// sample every unobserved variable from its CPT and weight the
+	// particle by the likelihood of the observed variables
+	BootstrapProposal Proposal = iota
+	// sample every unobserved variable from its CPT times the
+	// likelihood of its observed children in the slice, and weight
+	// the particle by the normalizers. Lowers the variance of the
+	// weights when the observations are unlikely under the CPTs
+	LikelihoodWeightedProposal
+)
+
+// How the particles are resampled from their weights
+type Resampling int
+
+const (
+	// one uniform draw, spread over N evenly spaced positions
+	SystematicResampling Resampling = iota
+	// floor(N*w) copies of every particle, the remaining
+	// particles are drawn from the residual weights
+	ResidualResampling
+)
+
+// Options of a particle filter, the zero value of a field
+// selects its default
+type ParticleFilterOptions struct {
+	// number of particles, default 1000
+	Particles int
+	Proposal  Proposal
+	Resample  Resampling
+	// resample once the effective sample size drops below
+	// Threshold * Particles, default 0.5. 1 resamples every step
+	Threshold float64
+}
+
+type ParticleFilter struct {
+	dbn  *DynamicBayesianNetwork
+	opts ParticleFilterOptions
+	// position of every variable in a particle
+	index map[string]int
+	// particles[i][j] is the bit of variable j in particle i,
+	// nil before the first step
+	particles [][]int
+	// normalized weights of the particles
+	weights []float64
+	// the number of steps taken
+	step int
+}
+
+// Creates a particle filter over the dynamic network, opts may be nil
+// - panics on invalid options
+func NewParticleFilter(dbn *DynamicBayesianNetwork, opts *ParticleFilterOptions) *ParticleFilter {
+	pf := &ParticleFilter{
+		dbn: dbn,
+		opts: ParticleFilterOptions{
+			Particles: 1000,
+			Threshold: 0.5,
+		},
+		index: make(map[string]int, len(dbn.variables)),
+	}
+	if opts != nil {
+		if opts.Particles < 0 || opts.Threshold < 0 || opts.Threshold > 1 {
+			panic(fmt.Sprintf("invalid particle filter options: %+v", *opts))
+		}
+		if opts.Particles > 0 {
+			pf.opts.Particles = opts.Particles
+		}
+		if opts.Threshold > 0 {
+			pf.opts.Threshold = opts.Threshold
+		}
+		pf.opts.Proposal = opts.Proposal
+		pf.opts.Resample = opts.Resample
+	}
+
+	for i, name := range dbn.variables {
+		pf.index[name] = i
+	}
+	pf.Reset()
+	return pf
+}
+
+// Restarts the filter from the prior slice
+func (pf *ParticleFilter) Reset() {
+	pf.particles = nil
+	pf.weights = make([]float64, pf.opts.Particles)
+	for i := range pf.weights {
+		pf.weights[i] = 1 / float64(pf.opts.Particles)
+	}
+	pf.step = 0
+}
+
+// the number of steps taken since the start
+func (pf *ParticleFilter) Steps() int {
+	return pf.step
+}
+
+// Advances the filter by one slice given the evidence of the slice,
+// which may be nil, and returns the filtered marginals of the slice.
+// - the filter is left unchanged if the evidence is invalid, or if
+//   it has zero probability under every particle
+func (pf *ParticleFilter) Step(evidence map[string]string) (StatMap, error) {
+	bits, err := pf.dbn.prior.evidenceBits(evidence)
+	if err != nil {
+		return nil, err
+	}
+
+	slice := pf.dbn.prior
+	if pf.step > 0 {
+		slice = pf.dbn.transition
+	}
+
+	particles := make([][]int, pf.opts.Particles)
+	weights := make([]float64, pf.opts.Particles)
+	total := 0.0
+	for i := range particles {
+		var previous []int
+		if pf.particles != nil {
+			previous = pf.particles[i]
+		}
+		var w float64
+		particles[i], w = pf.propagate(slice, previous, bits)
+		weights[i] = pf.weights[i] * w
+		total += weights[i]
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("step %d: evidence has zero probability under every particle", pf.step)
+	}
+
+	for i := range weights {
+		weights[i] /= total
+	}
+	pf.particles, pf.weights = particles, weights
+	pf.step++
+
+	// the estimate before resampling has the lower variance
+	stats := pf.Marginals()
+	if pf.EffectiveSampleSize() < pf.opts.Threshold*float64(pf.opts.Particles) {
+		pf.resample()
+	}
+	return stats, nil
+}
+
+// The weighted marginals of the current slice
+// - nil before the first step
+func (pf *ParticleFilter) Marginals() StatMap {
+	if pf.particles == nil {
+		return nil
+	}
+
+	stats := make(StatMap, len(pf.dbn.variables))
+	for j, name := range pf.dbn.variables {
+		pT := 0.0
+		for i, particle := range pf.particles {
+			if particle[j] == 0 {
+				pT += pf.weights[i]
+			}
+		}
+		stats[name] = []float64{pT, 1 - pT}
+	}
+	return stats
+}
+
+// The effective sample size 1 / sum(w^2) of the normalized weights
+func (pf *ParticleFilter) EffectiveSampleSize() float64 {
+	sum := 0.0
+	for _, w := range pf.weights {
+		sum += w * w
+	}
+	return 1 / sum
+}
+
+// samples the slice given the particle of the previous slice and
+// the evidence, returns the new particle and its incremental weight
+func (pf *ParticleFilter) propagate(slice *BayesianNetwork, previous []int, evidence map[string]int) ([]int, float64) {
+	current := make([]int, len(pf.dbn.variables))
+	for j := range current {
+		current[j] = -1
+	}
+	for name, bit := range evidence {
+		current[pf.index[name]] = bit
+	}
+
+	// observed variables whose likelihood is in the weight already
+	accounted := make([]bool, len(current))
+
+	w := 1.0
+	for _, node := range slice.nodeIndex {
+		j, ok := pf.index[node.Name()]
+		if !ok {
+			// placeholder of the previous slice
+			continue
+		}
+
+		if _, observed := evidence[node.Name()]; observed {
+			if !accounted[j] {
+				w *= pf.probability(node, current, previous)
+				accounted[j] = true
+			}
+			continue
+		}
+
+		current[j] = 0
+		pT := pf.probability(node, current, previous)
+		if pf.opts.Proposal == BootstrapProposal {
+			if rng.Float64() >= pT {
+				current[j] = 1
+			}
+			continue
+		}
+
+		// look ahead at the observed children whose
+		// other parents have been assigned
+		lookahead := make([]int, 0, node.NumChildren())
+		for _, child := range node.GetChildren() {
+			c := pf.index[child.Name()]
+			if _, observed := evidence[child.Name()]; observed && !accounted[c] && pf.assigned(child, current, previous) {
+				lookahead = append(lookahead, c)
+			}
+		}
+
+		q := []float64{pT, 1 - pT}
+		for bit := range q {
+			current[j] = bit
+			for _, c := range lookahead {
+				q[bit] *= pf.probability(slice.nodes[pf.dbn.variables[c]], current, previous)
+			}
+		}
+
+		z := q[0] + q[1]
+		if z == 0 {
+			return current, 0
+		}
+		current[j] = 0
+		if rng.Float64()*z >= q[0] {
+			current[j] = 1
+		}
+		w *= z
+		for _, c := range lookahead {
+			accounted[c] = true
+		}
+	}
+	return current, w
+}
+
+// the probability of the value of the node in the current
+// slice given the values of its parents
+func (pf *ParticleFilter) probability(node *Node, current, previous []int) float64 {
+	key := make([]byte, len(node.parentNames))
+	for i, parent := range node.parentNames {
+		key[i] = bitState(pf.parentBit(parent, current, previous))[0]
+	}
+
+	p := node.prob("T")
+	if len(key) > 0 {
+		p = node.prob(string(key))
+	}
+	if current[pf.index[node.Name()]] == 1 {
+		return 1 - p
+	}
+	return p
+}
+
+// reports whether every parent of the node has a value
+func (pf *ParticleFilter) assigned(node *Node, current, previous []int) bool {
+	for _, parent := range node.parentNames {
+		if pf.parentBit(parent, current, previous) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// the bit of a parent, which is either a variable of the
+// current slice or of the previous slice
+func (pf *ParticleFilter) parentBit(parent string, current, previous []int) int {
+	if j, ok := pf.index[parent]; ok {
+		return current[j]
+	}
+	return previous[pf.index[strings.TrimSuffix(parent, previousSuffix)]]
+}
+
+// replaces the particles by a sample drawn from their weights,
+// with uniform weights
+func (pf *ParticleFilter) resample() {
+	var indices []int
+	switch pf.opts.Resample {
+	case ResidualResampling:
+		indices = residualResample(pf.weights)
+	default:
+		indices = systematicResample(pf.weights)
+	}
+
+	particles := make([][]int, len(indices))
+	for i, idx := range indices {
+		particles[i] = pf.particles[idx]
+	}
+	pf.particles = particles
+	for i := range pf.weights {
+		pf.weights[i] = 1 / float64(len(pf.weights))
+	}
+}
+
+// draws len(weights) indices at the positions (u + i) / N
+// of the cumulative weights, for a single uniform u
+func systematicResample(weights []float64) []int {
+	n := len(weights)
+	indices := make([]int, 0, n)
+
+	u := rng.Float64() / float64(n)
+	cumulative := weights[0]
+	j := 0
+	for i := 0; i < n; i++ {
+		position := u + float64(i)/float64(n)
+		for position > cumulative && j < n-1 {
+			j++
+			cumulative += weights[j]
+		}
+		indices = append(indices, j)
+	}
+	return indices
+}
+
+// keeps floor(N*w) copies of every index and draws the
+// remaining indices from the residual weights
+func residualResample(weights []float64) []int {
+	n := len(weights)
+	indices := make([]int, 0, n)
+
+	residuals := make([]float64, n)
+	for i, w := range weights {
+		copies := int(math.Floor(float64(n) * w))
+		for k := 0; k < copies; k++ {
+			indices = append(indices, i)
+		}
+		residuals[i] = float64(n)*w - float64(copies)
+	}
+
+	remaining := n - len(indices)
+	if remaining == 0 {
+		return indices
+	}
+
+	total := 0.0
+	for _, r := range residuals {
+		total += r
+	}
+	for k := 0; k < remaining; k++ {
+		u := rng.Float64() * total
+		j := 0
+		for ; j < n-1 && u >= residuals[j]; j++ {
+			u -= residuals[j]
+		}
+		indices = append(indices, j)
+	}
+	return indices
+}
@@ -0,0 +1,96 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParticleFilter(t *testing.T) {
+	dbn := BuildMachineNetwork()
+	observations := []map[string]string{
+		{"Alarm": "F"},
+		nil,
+		{"Alarm": "T", "Load": "T"},
+		{"Alarm": "T"},
+		{"Alarm": "F"},
+	}
+	exact, err := dbn.Filter(observations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []ParticleFilterOptions{
+		{Particles: 5000, Proposal: BootstrapProposal, Resample: SystematicResampling},
+		{Particles: 5000, Proposal: LikelihoodWeightedProposal, Resample: ResidualResampling},
+		{Particles: 5000, Proposal: LikelihoodWeightedProposal, Threshold: 1},
+	} {
+		pf := NewParticleFilter(dbn, &opts)
+		for s, evidence := range observations {
+			stats, err := pf.Step(evidence)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range dbn.Variables() {
+				if math.Abs(stats[name][0]-exact[s][name][0]) > epsilon {
+					t.Errorf("%+v: P(%s=T) at step %d: Exp %.4f != %.4f Act",
+						opts, name, s, exact[s][name][0], stats[name][0])
+				}
+			}
+		}
+		if pf.Steps() != len(observations) {
+			t.Errorf("Exp %d != %d Act steps", len(observations), pf.Steps())
+		}
+		if opts.Threshold == 1 && pf.EffectiveSampleSize() < float64(opts.Particles)-1e-6 {
+			t.Errorf("the particles should be resampled after every step: ESS %.1f", pf.EffectiveSampleSize())
+		}
+	}
+}
+
+func TestParticleFilterImpossibleEvidence(t *testing.T) {
+	prior := BayNodes{
+		NewRootNode("A", 1),
+		NewNode("B", []string{"A"}, map[string]float64{"T": 1, "F": 0}),
+	}
+	transition := BayNodes{
+		NewNode("A", []string{Previous("A")}, map[string]float64{"T": 1, "F": 0}),
+		NewNode("B", []string{"A"}, map[string]float64{"T": 1, "F": 0}),
+	}
+	pf := NewParticleFilter(NewDynamicBayesianNetwork(prior, transition), &ParticleFilterOptions{Particles: 10})
+
+	if _, err := pf.Step(map[string]string{"B": "F"}); err == nil {
+		t.Fatalf("expected an error for impossible evidence")
+	}
+	if pf.Steps() != 0 || pf.Marginals() != nil {
+		t.Errorf("the filter should be left unchanged")
+	}
+}
+
+func TestResampling(t *testing.T) {
+	weights := []float64{0.5, 0.25, 0.125, 0.125, 0}
+
+	for name, resample := range map[string]func([]float64) []int{
+		"systematic": systematicResample,
+		"residual":   residualResample,
+	} {
+		counts := make([]int, len(weights))
+		indices := resample(weights)
+		if len(indices) != len(weights) {
+			t.Fatalf("%s: Exp %d != %d Act indices", name, len(weights), len(indices))
+		}
+		for _, i := range indices {
+			counts[i]++
+		}
+
+		// both schemes keep at least floor(N*w) copies of every
+		// particle and never draw a particle without weight
+		for i, w := range weights {
+			n := w * float64(len(weights))
+			if float64(counts[i]) < math.Floor(n) || w == 0 && counts[i] != 0 {
+				t.Errorf("%s: particle %d with weight %.3f has %d copies", name, i, w, counts[i])
+			}
+			if name == "systematic" && float64(counts[i]) > math.Ceil(n) {
+				t.Errorf("%s: particle %d with weight %.3f has %d copies", name, i, w, counts[i])
+			}
+		}
+	}
+}
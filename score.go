@@ -0,0 +1,151 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+)
+
+// Log joint probability of a complete assignment of the network,
+// computed from P()/PFalse() of every node
+func (bn *BayesianNetwork) LogProbability(assignment map[string]string) (float64, error) {
+	bn.Reset()
+	if err := bn.UpdateGraphValues(assignment); err != nil {
+		return 0, err
+	}
+	// cleanup
+	defer bn.Reset()
+
+	for _, node := range bn.nodeIndex {
+		if _, err := stateBit(node.GetAssignment()); err != nil {
+			return 0, fmt.Errorf("%s: %v", node.Name(), err)
+		}
+	}
+
+	logP := 0.0
+	for _, node := range bn.nodeIndex {
+		if node.GetAssignment() == "T" {
+			logP += math.Log(node.P())
+		} else {
+			logP += math.Log(node.PFalse())
+		}
+	}
+	return logP, nil
+}
+
+// Log probability of every row of the dataset.
+// - complete rows use the joint probability of LogProbability
+// - rows with missing values, and nodes without a column, are
+//   summed out exactly (the marginal likelihood of the row)
+// low values flag rows that are unlikely under the model
+func (bn *BayesianNetwork) RowLogProbabilities(ds *Dataset) ([]float64, error) {
+	if err := bn.checkColumns(ds); err != nil {
+		return nil, err
+	}
+
+	complete := len(ds.Columns()) == len(bn.nodeIndex)
+
+	logPs := make([]float64, ds.Len())
+	for i := range logPs {
+		var err error
+		if complete && ds.IsComplete(i) {
+			logPs[i], err = bn.LogProbability(ds.Row(i))
+		} else {
+			logPs[i], err = bn.LogEvidenceProbability(ds.Row(i))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", i, err)
+		}
+	}
+	return logPs, nil
+}
+
+// Log-likelihood of complete data: the sum of the log joint
+// probabilities of the rows. Missing values are reported as errors,
+// see MarginalLogLikelihood
+func (bn *BayesianNetwork) LogLikelihood(ds *Dataset) (float64, error) {
+	if err := bn.checkColumns(ds); err != nil {
+		return 0, err
+	}
+	if len(ds.Columns()) != len(bn.nodeIndex) {
+		return 0, fmt.Errorf("dataset has %d columns, the network has %d nodes", len(ds.Columns()), len(bn.nodeIndex))
+	}
+
+	ll := 0.0
+	for i := 0; i < ds.Len(); i++ {
+		if !ds.IsComplete(i) {
+			return 0, fmt.Errorf("row %d has missing values", i)
+		}
+		logP, err := bn.LogProbability(ds.Row(i))
+		if err != nil {
+			return 0, fmt.Errorf("row %d: %v", i, err)
+		}
+		ll += logP
+	}
+	return ll, nil
+}
+
+// Log-likelihood of data with missing values, which are summed out
+func (bn *BayesianNetwork) MarginalLogLikelihood(ds *Dataset) (float64, error) {
+	logPs, err := bn.RowLogProbabilities(ds)
+	if err != nil {
+		return 0, err
+	}
+
+	ll := 0.0
+	for _, logP := range logPs {
+		ll += logP
+	}
+	return ll, nil
+}
+
+// Number of free parameters of the network: one per CPT row,
+// or the parameters of the CPD or linear Gaussian of a node
+func (bn *BayesianNetwork) NumParameters() int {
+	k := 0
+	for _, node := range bn.nodeIndex {
+		if node.cpd != nil {
+			k += node.cpd.NumParameters()
+			continue
+		}
+		if node.gaussians != nil {
+			// the intercept, weights and variance of every row
+			for _, lg := range node.gaussians {
+				k += len(lg.Weights) + 2
+			}
+			continue
+		}
+		k += len(node.rowKeys())
+	}
+	return k
+}
+
+// Bayesian Information Criterion LL - k/2 * ln(N), where LL is
+// the marginal log-likelihood of the data and k the number of
+// parameters. Higher is better
+func (bn *BayesianNetwork) BIC(ds *Dataset) (float64, error) {
+	ll, err := bn.MarginalLogLikelihood(ds)
+	if err != nil {
+		return 0, err
+	}
+	return ll - float64(bn.NumParameters())/2*math.Log(float64(ds.Len())), nil
+}
+
+// Akaike Information Criterion LL - k, on the same
+// scale as BIC. Higher is better
+func (bn *BayesianNetwork) AIC(ds *Dataset) (float64, error) {
+	ll, err := bn.MarginalLogLikelihood(ds)
+	if err != nil {
+		return 0, err
+	}
+	return ll - float64(bn.NumParameters()), nil
+}
+
+// every column of the dataset must be a node of the network
+func (bn *BayesianNetwork) checkColumns(ds *Dataset) error {
+	for _, column := range ds.Columns() {
+		if bn.nodes[column] == nil {
+			return fmt.Errorf("Node '%s' does not exist in network", column)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLogLikelihood(t *testing.T) {
+	Seed(7)
+	bn := BuildStudentNetwork()
+	ds := bn.SampleDataset(2000)
+
+	ll, err := bn.LogLikelihood(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the generating network should score better than an
+	// empty network with the same marginals
+	prior, err := bn.ExactInference(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := make([]*Node, 0, bn.NodeCount())
+	for _, node := range bn.GetNodes() {
+		nodes = append(nodes, NewRootNode(node.Name(), prior[node.Name()][0]))
+	}
+	empty := NewBayesianNetwork(nodes...)
+
+	emptyLL, err := empty.LogLikelihood(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ll <= emptyLL {
+		t.Errorf("LL %.2f of the generating network <= %.2f of the empty network", ll, emptyLL)
+	}
+
+	bic, _ := bn.BIC(ds)
+	aic, _ := bn.AIC(ds)
+	k := float64(bn.NumParameters())
+	if math.Abs(bic-(ll-k/2*math.Log(2000))) > 1e-9 || math.Abs(aic-(ll-k)) > 1e-9 {
+		t.Errorf("BIC %.2f AIC %.2f for LL %.2f and k %v", bic, aic, ll, k)
+	}
+}
+
+func TestMarginalLogLikelihood(t *testing.T) {
+	bn := BuildStudentNetwork()
+	ds := NewDataset("E", "P", "J")
+	ds.Append(map[string]string{"E": "T", "J": "F"})
+
+	if _, err := bn.LogLikelihood(ds); err == nil {
+		t.Error("expected an error on incomplete data")
+	}
+
+	logPs, err := bn.RowLogProbabilities(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sum the joint over every completion of the row
+	exp := 0.0
+	hidden := []string{"I", "D", "P", "R", "U"}
+	for i := 0; i < 1<<uint(len(hidden)); i++ {
+		row := map[string]string{"E": "T", "J": "F"}
+		for j, name := range hidden {
+			row[name] = bitState((i >> uint(j)) & 1)
+		}
+		logP, err := bn.LogProbability(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp += math.Exp(logP)
+	}
+
+	if math.Abs(math.Exp(logPs[0])-exp) > 1e-9 {
+		t.Errorf("P(row) Exp %.6f != %.6f Act", exp, math.Exp(logPs[0]))
+	}
+}
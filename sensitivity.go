@@ -0,0 +1,143 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// One-way sensitivity analysis of a posterior to the CPT parameters.
+// A parameter theta = P(node=T | parents=key) appears linearly in every
+// joint probability, with P(node=F | key) = 1-theta co-varying, so the
+// posterior of a query is a rational function of theta:
+//
+//	P(query=T | e)(theta) = (A*theta + B) / (C*theta + D)
+//
+// where A*theta + B is proportional to P(query=T, e) and C*theta + D
+// to P(e).
+// The coefficients are found from two evaluations of each.
+type Sensitivity struct {
+	Node string
+	// CPT row of the parameter, "T" for a root
+	Key string
+	// the current value of the parameter
+	Value float64
+	// coefficients of the sensitivity function
+	A, B, C, D float64
+	// derivative of the sensitivity function at the current value
+	Derivative float64
+	// the parameter can vary within [Lower, Upper] before the
+	// most likely state of the query changes
+	Lower, Upper float64
+}
+
+// the sensitivity function: P(query=T | e) for the parameter value theta
+func (s Sensitivity) Posterior(theta float64) float64 {
+	return (s.A*theta + s.B) / (s.C*theta + s.D)
+}
+
+// Computes the sensitivity of P(query=T | e) to every CPT parameter
+// of the network, ranked by influence: the absolute value of the
+// derivative at the current parameter value, largest first
+// - nodes with a CPD have no CPT parameters and are skipped
+func (bn *BayesianNetwork) Sensitivity(query string, evidence map[string]string) ([]Sensitivity, error) {
+	result := make([]Sensitivity, 0, bn.NumParameters())
+	for _, node := range bn.nodeIndex {
+		if node.cpd != nil {
+			continue
+		}
+		for _, key := range node.rowKeys() {
+			s, err := bn.SensitivityFunction(query, evidence, node.Name(), key)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, s)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return math.Abs(result[i].Derivative) > math.Abs(result[j].Derivative)
+	})
+	return result, nil
+}
+
+// Computes the sensitivity of P(query=T | e) to the parameter
+// P(node=T | parents=key), see Sensitivity
+// - the network is modified while the function is computed
+func (bn *BayesianNetwork) SensitivityFunction(query string, evidence map[string]string, node, key string) (Sensitivity, error) {
+	n := bn.nodes[node]
+	if n == nil {
+		return Sensitivity{}, fmt.Errorf("Node '%s' does not exist in network", node)
+	}
+	value, ok := n.cpt[key]
+	if !ok || len(n.parentNames) == 0 && key != "T" {
+		return Sensitivity{}, fmt.Errorf("%s has no CPT row '%s'", node, key)
+	}
+	if bn.nodes[query] == nil {
+		return Sensitivity{}, fmt.Errorf("Node '%s' does not exist in network", query)
+	}
+	if _, err := bn.evidenceBits(evidence); err != nil {
+		return Sensitivity{}, err
+	}
+	if e, ok := evidence[query]; ok {
+		// a constant function
+		p := 0.0
+		if e == "T" {
+			p = 1
+		}
+		return Sensitivity{Node: node, Key: key, Value: value, B: p, D: 1, Lower: 0, Upper: 1}, nil
+	}
+
+	// probabilities relative to P(e) at the current value,
+	// so that small probabilities do not underflow
+	logE, err := bn.LogEvidenceProbability(evidence)
+	if err != nil {
+		return Sensitivity{}, err
+	}
+	if math.IsInf(logE, -1) {
+		return Sensitivity{}, fmt.Errorf("evidence has zero probability")
+	}
+	joint := with(evidence, query, "T")
+
+	defer func() {
+		n.cpt[key] = value
+	}()
+	var at [2][2]float64
+	for i, theta := range []float64{0, 1} {
+		n.cpt[key] = theta
+		for j, e := range []map[string]string{joint, evidence} {
+			logP, err := bn.LogEvidenceProbability(e)
+			if err != nil {
+				return Sensitivity{}, err
+			}
+			at[i][j] = math.Exp(logP - logE)
+		}
+	}
+
+	s := Sensitivity{
+		Node:  node,
+		Key:   key,
+		Value: value,
+		A:     at[1][0] - at[0][0],
+		B:     at[0][0],
+		C:     at[1][1] - at[0][1],
+		D:     at[0][1],
+	}
+	denominator := s.C*value + s.D
+	s.Derivative = (s.A*s.D - s.B*s.C) / (denominator * denominator)
+
+	// the function is monotone on [0, 1], so the most likely
+	// state changes at most once, where it crosses 0.5
+	s.Lower, s.Upper = 0, 1
+	if slope := s.A - 0.5*s.C; slope != 0 {
+		flip := (0.5*s.D - s.B) / slope
+		if flip > 0 && flip < 1 {
+			if value < flip {
+				s.Upper = flip
+			} else {
+				s.Lower = flip
+			}
+		}
+	}
+	return s, nil
+}
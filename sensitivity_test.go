@@ -0,0 +1,83 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSensitivityFunction(t *testing.T) {
+	bn := BuildStudentNetwork()
+	evidence := map[string]string{"J": "T", "U": "F"}
+
+	s, err := bn.SensitivityFunction("I", evidence, "R", "TF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bn.GetNode("R").cpt["TF"] != s.Value {
+		t.Fatalf("the parameter was not restored: %v", bn.GetNode("R").cpt)
+	}
+
+	// the function matches exact inference for other parameter values
+	for _, theta := range []float64{s.Value, 0.1, 0.95} {
+		bn.GetNode("R").cpt["TF"] = theta
+		posterior, err := bn.ExactInference(evidence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(s.Posterior(theta)-posterior["I"][0]) > 1e-9 {
+			t.Errorf("theta %.2f: Exp %.6f != %.6f Act", theta, posterior["I"][0], s.Posterior(theta))
+		}
+	}
+	bn.GetNode("R").cpt["TF"] = s.Value
+
+	h := 1e-6
+	numeric := (s.Posterior(s.Value+h) - s.Posterior(s.Value-h)) / (2 * h)
+	if math.Abs(numeric-s.Derivative) > 1e-6 {
+		t.Errorf("derivative: Exp %.6f != %.6f Act", numeric, s.Derivative)
+	}
+
+	// the most likely state is the same within the range and
+	// changes just outside of it
+	likely := s.Posterior(s.Value) > 0.5
+	for _, bound := range []float64{s.Lower, s.Upper} {
+		if bound == 0 || bound == 1 {
+			continue
+		}
+		if math.Abs(s.Posterior(bound)-0.5) > 1e-9 {
+			t.Errorf("bound %.4f: posterior %.4f", bound, s.Posterior(bound))
+		}
+	}
+	for _, theta := range []float64{s.Lower + 1e-6, s.Upper - 1e-6} {
+		if (s.Posterior(theta) > 0.5) != likely {
+			t.Errorf("the most likely state changed within [%.4f, %.4f]", s.Lower, s.Upper)
+		}
+	}
+}
+
+func TestSensitivityRanking(t *testing.T) {
+	bn := BuildStudentNetwork()
+	result, err := bn.Sensitivity("D", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != bn.NumParameters() {
+		t.Fatalf("Exp %d != %d Act parameters", bn.NumParameters(), len(result))
+	}
+
+	// without evidence only the prior of D matters
+	if result[0].Node != "D" || math.Abs(result[0].Derivative-1) > 1e-9 {
+		t.Errorf("Exp D with derivative 1 != %s %.4f Act", result[0].Node, result[0].Derivative)
+	}
+	for i, s := range result {
+		if i > 0 && math.Abs(s.Derivative) > math.Abs(result[i-1].Derivative) {
+			t.Errorf("parameters are not ranked by influence")
+		}
+		if s.Node != "D" && math.Abs(s.Derivative) > 1e-9 {
+			t.Errorf("%s %s should not influence D: %.6f", s.Node, s.Key, s.Derivative)
+		}
+	}
+	// P(D=T) = 0.2 flips at 0.5
+	if math.Abs(result[0].Upper-0.5) > 1e-9 || result[0].Lower != 0 {
+		t.Errorf("Exp [0, 0.5] != [%.4f, %.4f] Act", result[0].Lower, result[0].Upper)
+	}
+}
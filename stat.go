@@ -10,22 +10,33 @@ type NetworkStat struct {
 	bn    *BayesianNetwork
 	count []int
 	total int
+	// sums of the values and their squares of continuous nodes
+	sum, squares []float64
 }
 
 type StatMap map[string][]float64
 
 func NewNetworkStat(bn *BayesianNetwork) *NetworkStat {
 	return &NetworkStat{
-		bn:    bn,
-		total: 0,
-		count: make([]int, len(bn.nodeIndex)),
+		bn:      bn,
+		total:   0,
+		count:   make([]int, len(bn.nodeIndex)),
+		sum:     make([]float64, len(bn.nodeIndex)),
+		squares: make([]float64, len(bn.nodeIndex)),
 	}
 }
 
 // run through the entire network and increment
 // if the value is "T", else ignore
+// - continuous nodes add up their values
 func (stat *NetworkStat) Update() {
 	for i, node := range stat.bn.nodeIndex {
+		if node.IsContinuous() {
+			v := node.Value()
+			stat.sum[i] += v
+			stat.squares[i] += v * v
+			continue
+		}
 		assignment := node.GetAssignment()
 		if assignment == "F" {
 			continue
@@ -36,10 +47,17 @@ func (stat *NetworkStat) Update() {
 }
 
 // return a mapping of the normalized probablilities
+// - continuous nodes get their sample [mean, variance]
 func (stat *NetworkStat) GetStats() StatMap {
 	stats := make(map[string][]float64, len(stat.count))
 
 	for i, node := range stat.bn.GetNodes() {
+		if node.IsContinuous() {
+			n := float64(stat.total)
+			mean := stat.sum[i] / n
+			stats[node.Name()] = []float64{mean, stat.squares[i]/n - mean*mean}
+			continue
+		}
 		stats[node.Name()] = []float64{
 			float64(stat.count[i]) / float64(stat.total),
 			float64(stat.total-stat.count[i]) / float64(stat.total),
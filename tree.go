@@ -0,0 +1,326 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Tree-structured and rule-based CPDs, for nodes whose CPT rows are
+// equal for whole sets of parent assignments (context-specific
+// independence). Only the distinct rows are stored, and variable
+// elimination leaves out the parents that do not matter given the
+// evidence, see reducedFactor.
+
+// A decision tree over the parents of a node: a split tests a parent
+// and continues in the subtree of its value, a leaf holds P(node=T)
+// for every parent assignment that reaches it
+type CPDTree struct {
+	// the parent tested by a split, "" for a leaf
+	Parent string
+	T, F   *CPDTree
+	P      float64
+}
+
+// Generate a leaf with P(node=T) = p
+func TreeLeaf(p float64) *CPDTree {
+	return &CPDTree{P: p}
+}
+
+// Generate a split on the parent
+func TreeSplit(parent string, t, f *CPDTree) *CPDTree {
+	return &CPDTree{Parent: parent, T: t, F: f}
+}
+
+func (tree *CPDTree) isLeaf() bool {
+	return tree.Parent == ""
+}
+
+// the number of leaves of the tree
+func (tree *CPDTree) Leaves() int {
+	if tree.isLeaf() {
+		return 1
+	}
+	return tree.T.Leaves() + tree.F.Leaves()
+}
+
+type treeCPD struct {
+	// position of every parent in the key
+	parents map[string]int
+	root    *CPDTree
+}
+
+// Generate a node whose CPT is given by the tree over its parents
+func NewTreeNode(name string, parents []string, tree *CPDTree) *Node {
+	return NewCPDNode(name, parents, &treeCPD{
+		parents: positions(parents),
+		root:    tree,
+	})
+}
+
+func positions(names []string) map[string]int {
+	pos := make(map[string]int, len(names))
+	for i, name := range names {
+		pos[name] = i
+	}
+	return pos
+}
+
+func (t *treeCPD) Probability(key string) float64 {
+	tree := t.root
+	for !tree.isLeaf() {
+		if key[t.parents[tree.Parent]] == 'T' {
+			tree = tree.T
+		} else {
+			tree = tree.F
+		}
+	}
+	return tree.P
+}
+
+func (t *treeCPD) NumParameters() int {
+	return t.root.Leaves()
+}
+
+// the tree is complete if every split has both subtrees and no path
+// tests a parent twice
+func (t *treeCPD) Validate(numParents int) error {
+	if len(t.parents) != numParents {
+		return fmt.Errorf("tree CPD over %d parents, expected %d", len(t.parents), numParents)
+	}
+	if t.root == nil {
+		return fmt.Errorf("tree CPD has no tree")
+	}
+	return t.validate(t.root, make(map[string]bool))
+}
+
+func (t *treeCPD) validate(tree *CPDTree, path map[string]bool) error {
+	if tree.isLeaf() {
+		if tree.P < 0 || tree.P > 1 {
+			return fmt.Errorf("tree CPD leaf %f is not in [0, 1]", tree.P)
+		}
+		return nil
+	}
+	if _, ok := t.parents[tree.Parent]; !ok {
+		return fmt.Errorf("tree CPD splits on '%s', which is not a parent", tree.Parent)
+	}
+	if path[tree.Parent] {
+		return fmt.Errorf("tree CPD splits on '%s' twice on a path", tree.Parent)
+	}
+	if tree.T == nil || tree.F == nil {
+		return fmt.Errorf("tree CPD split on '%s' is missing a subtree", tree.Parent)
+	}
+
+	path[tree.Parent] = true
+	defer delete(path, tree.Parent)
+	if err := t.validate(tree.T, path); err != nil {
+		return err
+	}
+	return t.validate(tree.F, path)
+}
+
+// the parents tested on the paths that agree with the observed parents
+func (t *treeCPD) relevant(key []byte) []int {
+	seen := make(map[int]bool)
+	var walk func(*CPDTree)
+	walk = func(tree *CPDTree) {
+		if tree.isLeaf() {
+			return
+		}
+		pos := t.parents[tree.Parent]
+		switch key[pos] {
+		case 'T':
+			walk(tree.T)
+		case 'F':
+			walk(tree.F)
+		default:
+			seen[pos] = true
+			walk(tree.T)
+			walk(tree.F)
+		}
+	}
+	walk(t.root)
+	return sortedKeys(seen)
+}
+
+func sortedKeys(set map[int]bool) []int {
+	keys := make([]int, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// Returns the smallest tree that tests the parents in their order,
+// with the same probabilities as the rows of the node
+func (self *Node) CompressCPT() *CPDTree {
+	key := make([]byte, len(self.parentNames))
+	for i := range key {
+		key[i] = '?'
+	}
+	return self.compress(key)
+}
+
+func (self *Node) compress(key []byte) *CPDTree {
+	for i, parent := range self.parentNames {
+		if key[i] != '?' || !self.dependsOn(key, i) {
+			continue
+		}
+		key[i] = 'T'
+		t := self.compress(key)
+		key[i] = 'F'
+		f := self.compress(key)
+		key[i] = '?'
+		return TreeSplit(parent, t, f)
+	}
+
+	// the rows that agree with the key are equal
+	row := make([]byte, len(key))
+	for i, b := range key {
+		row[i] = b
+		if b == '?' {
+			row[i] = 'T'
+		}
+	}
+	if len(row) == 0 {
+		return TreeLeaf(self.prob("T"))
+	}
+	return TreeLeaf(self.prob(string(row)))
+}
+
+// reports whether flipping parent i changes a row that agrees with the key
+func (self *Node) dependsOn(key []byte, i int) bool {
+	var free []int
+	for j, b := range key {
+		if b == '?' && j != i {
+			free = append(free, j)
+		}
+	}
+
+	row := make([]byte, len(key))
+	copy(row, key)
+	for idx := 0; idx < 1<<uint(len(free)); idx++ {
+		for j, pos := range free {
+			row[pos] = bitState(bitOf(idx, j, len(free)))[0]
+		}
+		row[i] = 'T'
+		t := self.prob(string(row))
+		row[i] = 'F'
+		if t != self.prob(string(row)) {
+			return true
+		}
+	}
+	return false
+}
+
+// A rule of a rule-based CPD: P(node=T) = P for every assignment of
+// the parents that agrees with the context, e.g.
+// CPDRule{Context: map[string]string{"A": "T"}, P: 0.9}
+type CPDRule struct {
+	Context map[string]string
+	P       float64
+}
+
+type ruleCPD struct {
+	// position of every parent in the key
+	parents map[string]int
+	rules   []CPDRule
+}
+
+// Generate a node whose CPT is given by rules over its parents. The
+// rules must be mutually exclusive and cover every parent assignment
+func NewRuleNode(name string, parents []string, rules []CPDRule) *Node {
+	return NewCPDNode(name, parents, &ruleCPD{
+		parents: positions(parents),
+		rules:   rules,
+	})
+}
+
+// the probability of the rule that matches the key,
+// NaN if there is none, which Validate reports
+func (r *ruleCPD) Probability(key string) float64 {
+	for _, rule := range r.rules {
+		if r.matches(rule, func(pos int) byte { return key[pos] }) {
+			return rule.P
+		}
+	}
+	return math.NaN()
+}
+
+// reports whether the values of the parents agree with the context,
+// where a value of '?' agrees with both
+func (r *ruleCPD) matches(rule CPDRule, value func(pos int) byte) bool {
+	for parent, state := range rule.Context {
+		v := value(r.parents[parent])
+		if v != '?' && v != state[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *ruleCPD) NumParameters() int {
+	return len(r.rules)
+}
+
+// the rules are complete if no two rules agree on any parent assignment
+// and together they cover all 2^n assignments: a rule with a context
+// over k parents covers 2^(n-k) of them
+func (r *ruleCPD) Validate(numParents int) error {
+	if len(r.parents) != numParents {
+		return fmt.Errorf("rule CPD over %d parents, expected %d", len(r.parents), numParents)
+	}
+
+	covered := 0.0
+	for i, rule := range r.rules {
+		if rule.P < 0 || rule.P > 1 {
+			return fmt.Errorf("rule CPD probability %f is not in [0, 1]", rule.P)
+		}
+		for parent, state := range rule.Context {
+			if _, ok := r.parents[parent]; !ok {
+				return fmt.Errorf("rule CPD context has '%s', which is not a parent", parent)
+			}
+			if _, err := stateBit(state); err != nil {
+				return fmt.Errorf("rule CPD context: %s: %v", parent, err)
+			}
+		}
+		for _, other := range r.rules[:i] {
+			if compatible(rule.Context, other.Context) {
+				return fmt.Errorf("rule CPD contexts %v and %v overlap", other.Context, rule.Context)
+			}
+		}
+		covered += math.Pow(2, -float64(len(rule.Context)))
+	}
+	if covered != 1 {
+		return fmt.Errorf("rule CPD covers %.0f of %.0f parent assignments",
+			covered*math.Pow(2, float64(numParents)), math.Pow(2, float64(numParents)))
+	}
+	return nil
+}
+
+// reports whether two contexts agree on every parent they share
+func compatible(a, b map[string]string) bool {
+	for parent, state := range a {
+		if other, ok := b[parent]; ok && other != state {
+			return false
+		}
+	}
+	return true
+}
+
+// the context parents of the rules that agree with the observed parents
+func (r *ruleCPD) relevant(key []byte) []int {
+	seen := make(map[int]bool)
+	for _, rule := range r.rules {
+		if !r.matches(rule, func(pos int) byte { return key[pos] }) {
+			continue
+		}
+		for parent := range rule.Context {
+			if pos := r.parents[parent]; key[pos] == '?' {
+				seen[pos] = true
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
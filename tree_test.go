@@ -0,0 +1,146 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+// an alarm that only depends on the burglary sensor
+// when the system is armed
+func alarmTree() *CPDTree {
+	return TreeSplit("Armed",
+		TreeSplit("Motion",
+			TreeSplit("Pet", TreeLeaf(0.3), TreeLeaf(0.95)),
+			TreeLeaf(0.01)),
+		TreeLeaf(0))
+}
+
+func BuildArmedNetwork(alarm *Node) *BayesianNetwork {
+	return NewBayesianNetwork(
+		NewRootNode("Armed", 0.6),
+		NewRootNode("Motion", 0.1),
+		NewRootNode("Pet", 0.5),
+		alarm,
+		NewNode("Call", []string{"Alarm"}, map[string]float64{"T": 0.9, "F": 0.05}),
+	)
+}
+
+func TestTreeCPD(t *testing.T) {
+	parents := []string{"Armed", "Motion", "Pet"}
+	tree := NewTreeNode("Alarm", parents, alarmTree())
+	if k := tree.cpd.NumParameters(); k != 4 {
+		t.Errorf("Exp 4 != %d Act leaves", k)
+	}
+	if p := tree.prob("TTF"); p != 0.95 {
+		t.Errorf("P(Alarm|TTF): Exp 0.95 != %.2f Act", p)
+	}
+	if p := tree.prob("FTT"); p != 0 {
+		t.Errorf("P(Alarm|FTT): Exp 0 != %.2f Act", p)
+	}
+
+	// the table of the tree compresses back into the tree
+	table := make(map[string]float64)
+	for _, key := range cptKeys(3) {
+		table[key] = tree.prob(key)
+	}
+	cpt := NewNode("Alarm", parents, table)
+	if leaves := cpt.CompressCPT().Leaves(); leaves != 4 {
+		t.Errorf("Exp 4 != %d Act leaves of the compressed CPT", leaves)
+	}
+
+	bn, full := BuildArmedNetwork(tree), BuildArmedNetwork(cpt)
+	for _, evidence := range []map[string]string{
+		{},
+		{"Call": "T"},
+		{"Call": "T", "Armed": "T"},
+		{"Alarm": "F", "Pet": "T"},
+	} {
+		exp, err := full.ExactInference(evidence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		act, err := bn.ExactInference(evidence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compareExact(t, evidence, exp, act)
+	}
+
+	for _, invalid := range []*CPDTree{
+		TreeSplit("Armed", TreeLeaf(0.5), nil),
+		TreeSplit("Armed", TreeSplit("Armed", TreeLeaf(0), TreeLeaf(1)), TreeLeaf(1)),
+		TreeSplit("Unknown", TreeLeaf(0), TreeLeaf(1)),
+		TreeSplit("Armed", TreeLeaf(1.5), TreeLeaf(1)),
+	} {
+		if err := NewTreeNode("Alarm", parents, invalid).validateCPT(3); err == nil {
+			t.Errorf("Expected an error for the tree %+v", invalid)
+		}
+	}
+}
+
+func TestReducedFactor(t *testing.T) {
+	bn := BuildArmedNetwork(NewTreeNode("Alarm", []string{"Armed", "Motion", "Pet"}, alarmTree()))
+	alarm := bn.nodes["Alarm"]
+
+	// a disarmed system does not depend on the sensors
+	f := reducedFactor(alarm, map[string]int{"Armed": 1})
+	if len(f.vars) != 1 || f.vars[0] != "Alarm" {
+		t.Errorf("Exp [Alarm] != %v Act", f.vars)
+	}
+	// without motion, the pet does not matter
+	f = reducedFactor(alarm, map[string]int{"Motion": 1})
+	if len(f.vars) != 2 || f.vars[0] != "Armed" {
+		t.Errorf("Exp [Armed Alarm] != %v Act", f.vars)
+	}
+	if exp := nodeFactor(alarm).reduce(map[string]int{"Motion": 1}).reduce(map[string]int{"Pet": 0}); exp.values[0] != f.values[0] {
+		t.Errorf("Exp %v != %v Act", exp, f)
+	}
+}
+
+func TestRuleCPD(t *testing.T) {
+	parents := []string{"Armed", "Motion", "Pet"}
+	rules := NewRuleNode("Alarm", parents, []CPDRule{
+		{Context: map[string]string{"Armed": "F"}, P: 0},
+		{Context: map[string]string{"Armed": "T", "Motion": "F"}, P: 0.01},
+		{Context: map[string]string{"Armed": "T", "Motion": "T", "Pet": "T"}, P: 0.3},
+		{Context: map[string]string{"Armed": "T", "Motion": "T", "Pet": "F"}, P: 0.95},
+	})
+	tree := NewTreeNode("Alarm", parents, alarmTree())
+	for _, key := range cptKeys(3) {
+		if rules.prob(key) != tree.prob(key) {
+			t.Errorf("%s: Exp %.2f != %.2f Act", key, tree.prob(key), rules.prob(key))
+		}
+	}
+
+	bn, full := BuildArmedNetwork(rules), BuildArmedNetwork(tree)
+	evidence := map[string]string{"Call": "T", "Motion": "F"}
+	exp, err := full.ExactInference(evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	act, err := bn.ExactInference(evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareExact(t, evidence, exp, act)
+
+	for _, invalid := range [][]CPDRule{
+		// Armed=T, Motion=T is not covered
+		{{Context: map[string]string{"Armed": "F"}}, {Context: map[string]string{"Motion": "F", "Armed": "T"}}},
+		// overlap on Armed=T, Motion=F
+		{{Context: map[string]string{"Armed": "T"}}, {Context: map[string]string{"Motion": "F"}}},
+		{{Context: map[string]string{"Unknown": "T"}}},
+	} {
+		if err := NewRuleNode("Alarm", parents, invalid).validateCPT(3); err == nil {
+			t.Errorf("Expected an error for the rules %v", invalid)
+		}
+	}
+}
+
+func compareExact(t *testing.T, evidence map[string]string, exp, act StatMap) {
+	for name, dist := range exp {
+		if math.Abs(dist[0]-act[name][0]) > 1e-9 {
+			t.Errorf("%v: %s: Exp %.6f != %.6f Act", evidence, name, dist[0], act[name][0])
+		}
+	}
+}
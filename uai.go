@@ -0,0 +1,457 @@
+package BayesianNetwork
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// UAI inference competition formats
+// http://www.cs.huji.ac.il/project/PASCAL/fileFormat.php
+// - variables are identified by their position in the file.
+//   every function takes or returns that position as an order:
+//   order[i] is the name of the node of UAI variable i
+// - state 0 of a variable is mapped onto "T", state 1 onto "F"
+
+// tolerance on the sum of the entries of a table row
+const uaiTolerance = 1e-3
+
+// Decodes a .uai BAYES model. Variable i is named "Xi"
+// and the returned order lists those names
+func ReadUAI(r io.Reader) (*BayesianNetwork, []string, error) {
+	tokens, err := uaiTokens(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if t, _ := tokens.word(); t != "BAYES" {
+		return nil, nil, fmt.Errorf("UAI: unsupported model type '%s', expected BAYES", t)
+	}
+
+	n, err := tokens.int()
+	if err != nil {
+		return nil, nil, err
+	}
+	order := make([]string, n)
+	for i := range order {
+		order[i] = fmt.Sprintf("X%d", i)
+		card, err := tokens.int()
+		if err != nil {
+			return nil, nil, err
+		}
+		if card != 2 {
+			return nil, nil, fmt.Errorf("UAI: variable %d has cardinality %d, only binary variables are supported", i, card)
+		}
+	}
+
+	m, err := tokens.int()
+	if err != nil {
+		return nil, nil, err
+	}
+	if m != n {
+		return nil, nil, fmt.Errorf("UAI: BAYES model with %d variables has %d functions", n, m)
+	}
+
+	// the last variable of a scope is the child, the others its parents
+	scopes := make([][]string, m)
+	for i := range scopes {
+		size, err := tokens.int()
+		if err != nil {
+			return nil, nil, err
+		}
+		if size < 1 {
+			return nil, nil, fmt.Errorf("UAI: function %d has an empty scope", i)
+		}
+		for j := 0; j < size; j++ {
+			v, err := tokens.int()
+			if err != nil {
+				return nil, nil, err
+			}
+			if v < 0 || v >= n {
+				return nil, nil, fmt.Errorf("UAI: function %d refers to unknown variable %d", i, v)
+			}
+			scopes[i] = append(scopes[i], order[v])
+		}
+	}
+
+	nodes := make(BayNodes, 0, m)
+	defined := make(map[string]bool, n)
+	for i, scope := range scopes {
+		entries, err := tokens.int()
+		if err != nil {
+			return nil, nil, err
+		}
+		if entries != 1<<uint(len(scope)) {
+			return nil, nil, fmt.Errorf("UAI: function %d has %d entries, expected %d",
+				i, entries, 1<<uint(len(scope)))
+		}
+
+		values := make([]float64, entries)
+		for j := range values {
+			if values[j], err = tokens.float(); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		child := scope[len(scope)-1]
+		if defined[child] {
+			return nil, nil, fmt.Errorf("UAI: variable '%s' is the child of more than one function", child)
+		}
+		defined[child] = true
+
+		node, err := uaiNode(child, scope[:len(scope)-1], values)
+		if err != nil {
+			return nil, nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	bn, err := buildBayesianNetwork(nodes...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("UAI: %v", err)
+	}
+	return bn, order, nil
+}
+
+// builds the node from a table with the last parent varying
+// fastest, followed by the state of the child
+func uaiNode(name string, parents []string, values []float64) (*Node, error) {
+	rows := len(values) / 2
+	probs := make([]float64, rows)
+	for i := range probs {
+		t, f := values[2*i], values[2*i+1]
+		if t < 0 || f < 0 || math.Abs(t+f-1) > uaiTolerance {
+			return nil, fmt.Errorf("UAI: row %d of the table of '%s' is not a distribution: (%v, %v)",
+				i, name, t, f)
+		}
+		probs[i] = t / (t + f)
+	}
+
+	if len(parents) == 0 {
+		return NewRootNode(name, probs[0]), nil
+	}
+
+	cpt := make(map[string]float64, rows)
+	for i, key := range cptKeys(len(parents)) {
+		cpt[key] = probs[i]
+	}
+	return NewNode(name, parents, cpt), nil
+}
+
+// Encodes the network as a .uai BAYES model
+// - a nil order writes the nodes in the index order of the network
+func (bn *BayesianNetwork) WriteUAI(w io.Writer, order []string) error {
+	if err := bn.checkDiscrete(); err != nil {
+		return err
+	}
+	order, index, err := bn.uaiOrder(order)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("BAYES\n")
+	fmt.Fprintf(&buf, "%d\n", len(order))
+	buf.WriteString(strings.TrimSpace(strings.Repeat("2 ", len(order))))
+	fmt.Fprintf(&buf, "\n%d\n", len(order))
+
+	for _, name := range order {
+		node := bn.nodes[name]
+		parents := node.GetParentNames()
+		fmt.Fprintf(&buf, "%d", len(parents)+1)
+		for _, parent := range parents {
+			fmt.Fprintf(&buf, " %d", index[parent])
+		}
+		fmt.Fprintf(&buf, " %d\n", index[name])
+	}
+
+	for _, name := range order {
+		node := bn.nodes[name]
+		keys := node.rowKeys()
+		fmt.Fprintf(&buf, "\n%d\n", 2*len(keys))
+		for _, key := range keys {
+			p := node.prob(key)
+			fmt.Fprintf(&buf, " %s %s\n", formatProb(p), formatProb(1-p))
+		}
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// validates the order against the network and returns
+// it along with the position of every node
+func (bn *BayesianNetwork) uaiOrder(order []string) ([]string, map[string]int, error) {
+	if order == nil {
+		order = make([]string, 0, len(bn.nodeIndex))
+		for _, node := range bn.nodeIndex {
+			order = append(order, node.Name())
+		}
+	}
+
+	if len(order) != len(bn.nodeIndex) {
+		return nil, nil, fmt.Errorf("UAI: order has %d variables, the network has %d nodes",
+			len(order), len(bn.nodeIndex))
+	}
+
+	index := make(map[string]int, len(order))
+	for i, name := range order {
+		if bn.nodes[name] == nil {
+			return nil, nil, fmt.Errorf("UAI: Node '%s' does not exist in network", name)
+		}
+		if _, ok := index[name]; ok {
+			return nil, nil, fmt.Errorf("UAI: '%s' appears twice in the order", name)
+		}
+		index[name] = i
+	}
+	return order, index, nil
+}
+
+// Decodes a .evid file into one evidence map per sample.
+// Both the single-sample format of UAI 2014 ("<n> <var> <val> ...")
+// and the older format prefixed by the number of samples are read
+func ReadUAIEvidence(r io.Reader, order []string) ([]map[string]string, error) {
+	tokens, err := uaiTokens(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens.fields) == 0 {
+		return []map[string]string{{}}, nil
+	}
+
+	// single sample <=> "<n>" followed by exactly n pairs
+	samples := 1
+	if n, err := strconv.Atoi(tokens.fields[0]); err != nil || 1+2*n != len(tokens.fields) {
+		if samples, err = tokens.int(); err != nil {
+			return nil, err
+		}
+	}
+
+	evidence := make([]map[string]string, samples)
+	for s := range evidence {
+		n, err := tokens.int()
+		if err != nil {
+			return nil, err
+		}
+		evidence[s] = make(map[string]string, n)
+		for i := 0; i < n; i++ {
+			v, err := tokens.int()
+			if err != nil {
+				return nil, err
+			}
+			value, err := tokens.int()
+			if err != nil {
+				return nil, err
+			}
+			if v < 0 || v >= len(order) {
+				return nil, fmt.Errorf("UAI: evidence on unknown variable %d", v)
+			}
+			if value != 0 && value != 1 {
+				return nil, fmt.Errorf("UAI: evidence %d on binary variable %d", value, v)
+			}
+			evidence[s][order[v]] = bitState(value)
+		}
+	}
+
+	return evidence, nil
+}
+
+// Encodes the evidence in the single-sample .evid format
+func WriteUAIEvidence(w io.Writer, evidence map[string]string, order []string) error {
+	if err := checkOrder(evidence, order); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d", len(evidence))
+	for i, name := range order {
+		value, ok := evidence[name]
+		if !ok {
+			continue
+		}
+		bit, err := stateBit(value)
+		if err != nil {
+			return fmt.Errorf("UAI: %s: %v", name, err)
+		}
+		fmt.Fprintf(&buf, " %d %d", i, bit)
+	}
+	buf.WriteString("\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// reports a name in the map that is not part of the order
+func checkOrder(m map[string]string, order []string) error {
+	known := make(map[string]bool, len(order))
+	for _, name := range order {
+		known[name] = true
+	}
+	for name := range m {
+		if !known[name] {
+			return fmt.Errorf("UAI: '%s' is not part of the order", name)
+		}
+	}
+	return nil
+}
+
+// Encodes posterior marginals in the MAR result format
+func WriteUAIMAR(w io.Writer, stats StatMap, order []string) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "MAR\n%d", len(order))
+	for _, name := range order {
+		dist, ok := stats[name]
+		if !ok {
+			return fmt.Errorf("UAI: no marginal for '%s'", name)
+		}
+		fmt.Fprintf(&buf, " 2 %s %s", formatProb(dist[0]), formatProb(dist[1]))
+	}
+	buf.WriteString("\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Decodes a MAR result file
+func ReadUAIMAR(r io.Reader, order []string) (StatMap, error) {
+	tokens, err := uaiTokens(r)
+	if err != nil {
+		return nil, err
+	}
+	if t, _ := tokens.word(); t != "MAR" {
+		return nil, fmt.Errorf("UAI: expected MAR, got '%s'", t)
+	}
+
+	n, err := tokens.int()
+	if err != nil {
+		return nil, err
+	}
+	if n != len(order) {
+		return nil, fmt.Errorf("UAI: MAR has %d variables, expected %d", n, len(order))
+	}
+
+	stats := make(StatMap, n)
+	for _, name := range order {
+		card, err := tokens.int()
+		if err != nil {
+			return nil, err
+		}
+		if card != 2 {
+			return nil, fmt.Errorf("UAI: '%s' has cardinality %d in MAR", name, card)
+		}
+		dist := make([]float64, 2)
+		for j := range dist {
+			if dist[j], err = tokens.float(); err != nil {
+				return nil, err
+			}
+		}
+		stats[name] = dist
+	}
+	return stats, nil
+}
+
+// Encodes a most probable explanation in the MPE result format
+func WriteUAIMPE(w io.Writer, assignment map[string]string, order []string) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "MPE\n%d", len(order))
+	for _, name := range order {
+		bit, err := stateBit(assignment[name])
+		if err != nil {
+			return fmt.Errorf("UAI: %s: %v", name, err)
+		}
+		fmt.Fprintf(&buf, " %d", bit)
+	}
+	buf.WriteString("\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Decodes an MPE result file
+func ReadUAIMPE(r io.Reader, order []string) (map[string]string, error) {
+	tokens, err := uaiTokens(r)
+	if err != nil {
+		return nil, err
+	}
+	if t, _ := tokens.word(); t != "MPE" {
+		return nil, fmt.Errorf("UAI: expected MPE, got '%s'", t)
+	}
+
+	n, err := tokens.int()
+	if err != nil {
+		return nil, err
+	}
+	if n != len(order) {
+		return nil, fmt.Errorf("UAI: MPE has %d variables, expected %d", n, len(order))
+	}
+
+	assignment := make(map[string]string, n)
+	for _, name := range order {
+		bit, err := tokens.int()
+		if err != nil {
+			return nil, err
+		}
+		if bit != 0 && bit != 1 {
+			return nil, fmt.Errorf("UAI: '%s' has state %d in MPE", name, bit)
+		}
+		assignment[name] = bitState(bit)
+	}
+	return assignment, nil
+}
+
+// Encodes the log10 probability of the evidence in the PR result format
+func WriteUAIPR(w io.Writer, logProb float64) error {
+	_, err := fmt.Fprintf(w, "PR\n%s\n", formatProb(logProb/math.Ln10))
+	return err
+}
+
+// whitespace separated fields of a UAI file
+type uaiFields struct {
+	fields []string
+	pos    int
+}
+
+func uaiTokens(r io.Reader) (*uaiFields, error) {
+	src, err := ioutil.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	return &uaiFields{fields: strings.Fields(string(src))}, nil
+}
+
+func (t *uaiFields) word() (string, error) {
+	if t.pos >= len(t.fields) {
+		return "", fmt.Errorf("UAI: unexpected end of file")
+	}
+	t.pos++
+	return t.fields[t.pos-1], nil
+}
+
+func (t *uaiFields) int() (int, error) {
+	w, err := t.word()
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(w)
+	if err != nil {
+		return 0, fmt.Errorf("UAI: expected an integer, got '%s'", w)
+	}
+	return i, nil
+}
+
+func (t *uaiFields) float() (float64, error) {
+	w, err := t.word()
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(w, 64)
+	if err != nil {
+		return 0, fmt.Errorf("UAI: expected a number, got '%s'", w)
+	}
+	return f, nil
+}
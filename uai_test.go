@@ -0,0 +1,121 @@
+package BayesianNetwork
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+const uaiModel = `BAYES
+3
+2 2 2
+3
+1 0
+2 0 1
+2 1 2
+
+2
+ 0.436 0.564
+
+4
+ 0.128 0.872
+ 0.920 0.080
+
+4
+ 0.210 0.790
+ 0.333 0.667
+`
+
+func TestReadUAI(t *testing.T) {
+	bn, order, err := ReadUAI(strings.NewReader(uaiModel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 3 || bn.NodeCount() != 3 {
+		t.Fatalf("unexpected order %v", order)
+	}
+
+	x2 := bn.GetNode(order[2])
+	if !x2.validateParents([]string{order[1]}) {
+		t.Errorf("parents of X2: %v", x2.GetParentNames())
+	}
+	if math.Abs(x2.cpt["F"]-0.333) > 1e-12 {
+		t.Errorf("X2[F] = %v", x2.cpt["F"])
+	}
+
+	evidence, err := ReadUAIEvidence(strings.NewReader("1 2 0\n"), order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evidence) != 1 || evidence[0]["X2"] != "T" {
+		t.Errorf("evidence: %v", evidence)
+	}
+
+	// older format, prefixed by the number of samples
+	evidence, err = ReadUAIEvidence(strings.NewReader("2\n2 0 1 1 0\n0\n"), order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evidence) != 2 || evidence[0]["X0"] != "F" || evidence[0]["X1"] != "T" || len(evidence[1]) != 0 {
+		t.Errorf("evidence: %v", evidence)
+	}
+}
+
+func TestUAIRoundTrip(t *testing.T) {
+	bn := BuildStudentNetwork()
+
+	var buf bytes.Buffer
+	if err := bn.WriteUAI(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, order, err := ReadUAI(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// variable i of the file is node i of the index
+	for i, node := range bn.GetNodes() {
+		other := decoded.GetNode(order[i])
+		for _, key := range node.rowKeys() {
+			if math.Abs(node.cpt[key]-other.cpt[key]) > 1e-12 {
+				t.Errorf("%s[%s]: %v != %v", node.Name(), key, node.cpt[key], other.cpt[key])
+			}
+		}
+	}
+
+	stats, err := decoded.ExactInference(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	if err := WriteUAIMAR(&buf, stats, order); err != nil {
+		t.Fatal(err)
+	}
+	mar, err := ReadUAIMAR(&buf, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareStatMaps(stats, mar, t)
+
+	mpe, _, err := decoded.MPE(map[string]string{order[0]: "T"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := WriteUAIMPE(&buf, mpe, order); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadUAIMPE(&buf, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range mpe {
+		if read[name] != value {
+			t.Errorf("MPE: %v != %v", mpe, read)
+			break
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package BayesianNetwork
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Value of information: how much observing a node is expected to
+// tell about a target, or to improve the decisions of an
+// influence diagram, before the observation is made.
+
+// Expected reduction in the entropy of the target, in bits, from
+// observing each candidate given the evidence, i.e. the mutual
+// information I(target; candidate | evidence)
+// - candidates that are observed already have no value
+func (bn *BayesianNetwork) ValueOfInformation(target string, candidates []string, evidence map[string]string) (map[string]float64, error) {
+	bits, err := bn.evidenceBits(evidence)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range append([]string{target}, candidates...) {
+		if bn.nodes[name] == nil {
+			return nil, fmt.Errorf("Node '%s' does not exist in network", name)
+		}
+	}
+
+	voi := make(map[string]float64, len(candidates))
+	if _, ok := bits[target]; ok {
+		for _, c := range candidates {
+			voi[c] = 0
+		}
+		return voi, nil
+	}
+
+	prior, err := bn.posterior(target, bits)
+	if err != nil {
+		return nil, err
+	}
+	h := entropy(prior)
+
+	for _, c := range candidates {
+		if _, ok := bits[c]; ok {
+			voi[c] = 0
+			continue
+		}
+		if c == target {
+			voi[c] = h
+			continue
+		}
+
+		pc, err := bn.posterior(c, bits)
+		if err != nil {
+			return nil, err
+		}
+		expected := 0.0
+		for bit, p := range pc {
+			if p == 0 {
+				continue
+			}
+			observed := copyBits(bits)
+			observed[c] = bit
+			dist, err := bn.posterior(target, observed)
+			if err != nil {
+				return nil, err
+			}
+			expected += p * entropy(dist)
+		}
+		// rounding can make independent candidates slightly negative
+		voi[c] = math.Max(0, h-expected)
+	}
+	return voi, nil
+}
+
+// Expected gain in maximum expected utility from observing each
+// candidate before the decision is made, given the evidence known
+// before any decision
+// - candidates in the information set of the decision have no value
+// - reports an error if a candidate depends on the decision
+func (id *InfluenceDiagram) ValueOfInformation(decision string, candidates []string, evidence map[string]string) (map[string]float64, error) {
+	k := -1
+	for i, d := range id.decisions {
+		if d.name == decision {
+			k = i
+		}
+	}
+	if k < 0 {
+		return nil, fmt.Errorf("decision '%s' does not exist", decision)
+	}
+
+	_, meu, err := id.solve(evidence)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, name := range id.informationSet(k) {
+		known[name] = true
+	}
+
+	voi := make(map[string]float64, len(candidates))
+	for _, c := range candidates {
+		if _, ok := evidence[c]; ok || known[c] {
+			voi[c] = 0
+			continue
+		}
+
+		// the same diagram, where the decision also observes c
+		decisions := append([]*DecisionNode{}, id.decisions...)
+		informed := *id.decisions[k]
+		informed.informational = append(append([]string{}, informed.informational...), c)
+		decisions[k] = &informed
+
+		diagram, err := buildInfluenceDiagram(id.chance, decisions, id.utilities)
+		if err != nil {
+			return nil, err
+		}
+		_, informedMEU, err := diagram.solve(evidence)
+		if err != nil {
+			return nil, err
+		}
+		voi[c] = math.Max(0, informedMEU-meu)
+	}
+	return voi, nil
+}
+
+// Options of the greedy test selection, the zero value
+// of a field selects its default
+type TestSelectionOptions struct {
+	// cost of every test, default 1. Tests are ranked
+	// on their value of information per unit of cost
+	Costs map[string]float64
+	// stop once the most likely state of the target
+	// has this probability, default 0.95
+	Confidence float64
+	// only perform tests that gain more than this many bits
+	MinGain float64
+}
+
+// The unobserved candidate with the highest value of information
+// about the target per unit of cost, along with its value of
+// information. Returns "" if no candidate is worth observing
+func (bn *BayesianNetwork) NextTest(target string, candidates []string, evidence map[string]string, opts *TestSelectionOptions) (string, float64, error) {
+	if opts == nil {
+		opts = &TestSelectionOptions{}
+	}
+
+	voi, err := bn.ValueOfInformation(target, candidates, evidence)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// ties are broken on the name for repeatable results
+	sorted := append([]string{}, candidates...)
+	sort.Strings(sorted)
+
+	best, bestScore := "", 0.0
+	for _, c := range sorted {
+		if voi[c] <= opts.MinGain {
+			continue
+		}
+		cost := 1.0
+		if v, ok := opts.Costs[c]; ok {
+			cost = v
+		}
+		if cost <= 0 {
+			return "", 0, fmt.Errorf("test '%s' has cost %f, costs must be positive", c, cost)
+		}
+		if score := voi[c] / cost; best == "" || score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	if best == "" {
+		return "", 0, nil
+	}
+	return best, voi[best], nil
+}
+
+// Greedy sequential test selection for troubleshooting: performs
+// the NextTest, asks observe for its outcome and adds it to the
+// evidence, until the target reaches the confidence or no test is
+// worth performing. Returns the tests in the order they were
+// performed, along with the final evidence
+// - the given evidence is not modified
+func (bn *BayesianNetwork) SelectTests(target string, candidates []string, evidence map[string]string,
+	observe func(test string) (string, error), opts *TestSelectionOptions) ([]string, map[string]string, error) {
+	if opts == nil {
+		opts = &TestSelectionOptions{}
+	}
+	confidence := opts.Confidence
+	if confidence == 0 {
+		confidence = 0.95
+	}
+
+	evidence = merge(evidence, nil)
+	performed := make([]string, 0, len(candidates))
+	for {
+		if _, ok := evidence[target]; ok {
+			return performed, evidence, nil
+		}
+		bits, err := bn.evidenceBits(evidence)
+		if err != nil {
+			return nil, nil, err
+		}
+		dist, err := bn.posterior(target, bits)
+		if err != nil {
+			return nil, nil, err
+		}
+		if math.Max(dist[0], dist[1]) >= confidence {
+			return performed, evidence, nil
+		}
+
+		test, _, err := bn.NextTest(target, candidates, evidence, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if test == "" {
+			return performed, evidence, nil
+		}
+
+		outcome, err := observe(test)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := stateBit(outcome); err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", test, err)
+		}
+		evidence[test] = outcome
+		performed = append(performed, test)
+	}
+}
+
+// entropy of a distribution in bits
+func entropy(dist []float64) float64 {
+	h := 0.0
+	for _, p := range dist {
+		if p > 0 {
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
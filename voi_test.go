@@ -0,0 +1,137 @@
+package BayesianNetwork
+
+import (
+	"math"
+	"testing"
+)
+
+// a fault with three tests of different accuracy
+func BuildDiagnosisNetwork() *BayesianNetwork {
+	return NewBayesianNetwork(
+		NewRootNode("Fault", 0.5),
+		NewNode("A", []string{"Fault"}, map[string]float64{"T": 0.9, "F": 0.1}),
+		NewNode("B", []string{"Fault"}, map[string]float64{"T": 0.6, "F": 0.4}),
+		NewNode("C", []string{"Fault"}, map[string]float64{"T": 0.99, "F": 0.01}),
+	)
+}
+
+func TestValueOfInformation(t *testing.T) {
+	bn := BuildStudentNetwork()
+	evidence := map[string]string{"J": "T"}
+
+	voi, err := bn.ValueOfInformation("I", []string{"R", "P", "E", "J", "I"}, evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the mutual information is symmetric
+	back, err := bn.ValueOfInformation("R", []string{"I"}, evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(voi["R"]-back["I"]) > 1e-9 {
+		t.Errorf("I(I;R|J): Exp %.6f != %.6f Act", back["I"], voi["R"])
+	}
+
+	// an observed node has no value, and observing
+	// the target removes all of its entropy
+	if voi["J"] != 0 || voi["R"] <= 0 || voi["E"] <= 0 {
+		t.Errorf("unexpected values of information: %v", voi)
+	}
+	// E and I are only dependent through the observed J
+	independent, err := bn.ValueOfInformation("I", []string{"E"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if independent["E"] > 1e-9 {
+		t.Errorf("I(I;E): Exp 0 != %.6f Act", independent["E"])
+	}
+	posterior, err := bn.ExactInference(evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(voi["I"]-entropy(posterior["I"])) > 1e-9 {
+		t.Errorf("H(I|J): Exp %.6f != %.6f Act", entropy(posterior["I"]), voi["I"])
+	}
+}
+
+func TestDecisionValueOfInformation(t *testing.T) {
+	chance := BayNodes{
+		NewRootNode("Rain", 0.3),
+		NewNode("Forecast", []string{"Rain"}, map[string]float64{"T": 0.8, "F": 0.1}),
+	}
+	take := NewDecisionNode("Take", nil)
+	comfort := NewUtilityNode("Comfort", []string{"Rain", "Take"}, map[string]float64{
+		"TT": 70, "TF": 0, "FT": 20, "FF": 100})
+	id := NewInfluenceDiagram(chance, []*DecisionNode{take}, []*UtilityNode{comfort})
+
+	// 81.2 with the forecast, 70 without
+	voi, err := id.ValueOfInformation("Take", []string{"Forecast", "Rain"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(voi["Forecast"]-11.2) > 1e-9 {
+		t.Errorf("Forecast: Exp %.4f != %.4f Act", 11.2, voi["Forecast"])
+	}
+	// perfect information: 0.3*70 + 0.7*100 = 91
+	if math.Abs(voi["Rain"]-21) > 1e-9 {
+		t.Errorf("Rain: Exp %.4f != %.4f Act", 21.0, voi["Rain"])
+	}
+
+	// once the forecast is known, the rain has less value
+	voi, err = id.ValueOfInformation("Take", []string{"Rain"}, map[string]string{"Forecast": "F"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// P(Rain | Forecast=F) = 0.06/0.69, leaving the umbrella is right
+	// unless it rains: gain P(Rain|F=F) * 70
+	if exp := 0.06 / 0.69 * 70; math.Abs(voi["Rain"]-exp) > 1e-9 {
+		t.Errorf("Rain given the forecast: Exp %.4f != %.4f Act", exp, voi["Rain"])
+	}
+}
+
+func TestSelectTests(t *testing.T) {
+	bn := BuildDiagnosisNetwork()
+	candidates := []string{"A", "B", "C"}
+
+	test, _, err := bn.NextTest("Fault", candidates, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if test != "C" {
+		t.Errorf("Exp C != %s Act", test)
+	}
+	costly := &TestSelectionOptions{Costs: map[string]float64{"C": 10}}
+	test, _, err = bn.NextTest("Fault", candidates, nil, costly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if test != "A" {
+		t.Errorf("Exp A != %s Act", test)
+	}
+
+	// the fault is present: A leaves P(Fault) at 0.9, below the
+	// confidence, and C is then worth more per unit of cost than B
+	outcomes := map[string]string{"A": "T", "B": "T", "C": "T"}
+	observe := func(test string) (string, error) {
+		return outcomes[test], nil
+	}
+	performed, evidence, err := bn.SelectTests("Fault", candidates, nil, observe, costly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(performed) != 2 || performed[0] != "A" || performed[1] != "C" {
+		t.Errorf("Exp [A C] != %v Act", performed)
+	}
+	if len(evidence) != 2 {
+		t.Errorf("Exp 2 != %d Act observations", len(evidence))
+	}
+
+	// with C cheap, a single test reaches the confidence
+	performed, _, err = bn.SelectTests("Fault", candidates, nil, observe, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(performed) != 1 || performed[0] != "C" {
+		t.Errorf("Exp [C] != %v Act", performed)
+	}
+}
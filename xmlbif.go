@@ -0,0 +1,258 @@
+package BayesianNetwork
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// XMLBIF 0.3 as written by Weka, GeNIe and pgmpy
+// http://www.cs.cmu.edu/~fgcozman/Research/InterchangeFormat/
+type xmlBIF struct {
+	XMLName xml.Name      `xml:"BIF"`
+	Version string        `xml:"VERSION,attr"`
+	Network xmlBIFNetwork `xml:"NETWORK"`
+}
+
+type xmlBIFNetwork struct {
+	Name        string             `xml:"NAME"`
+	Properties  []string           `xml:"PROPERTY"`
+	Variables   []xmlBIFVariable   `xml:"VARIABLE"`
+	Definitions []xmlBIFDefinition `xml:"DEFINITION"`
+}
+
+type xmlBIFVariable struct {
+	Type       string   `xml:"TYPE,attr"`
+	Name       string   `xml:"NAME"`
+	Outcomes   []string `xml:"OUTCOME"`
+	Properties []string `xml:"PROPERTY"`
+}
+
+type xmlBIFDefinition struct {
+	For   string   `xml:"FOR"`
+	Given []string `xml:"GIVEN"`
+	Table string   `xml:"TABLE"`
+}
+
+// strip the whitespace that pretty-printed documents
+// leave around names and outcomes
+func (net *xmlBIFNetwork) trimNames() {
+	for i := range net.Variables {
+		v := &net.Variables[i]
+		v.Name = strings.TrimSpace(v.Name)
+		for j := range v.Outcomes {
+			v.Outcomes[j] = strings.TrimSpace(v.Outcomes[j])
+		}
+	}
+	for i := range net.Definitions {
+		def := &net.Definitions[i]
+		def.For = strings.TrimSpace(def.For)
+		for j := range def.Given {
+			def.Given[j] = strings.TrimSpace(def.Given[j])
+		}
+	}
+}
+
+// tolerance on the sum of the T and F entries of a TABLE row
+const xmlBIFTolerance = 1e-3
+
+// Decodes an XMLBIF 0.3 document into a network
+// - only binary "nature" variables are supported. The first
+//   OUTCOME of a variable is mapped onto "T", the second onto "F"
+// - the order of the GIVEN elements becomes the parent order
+//   of the node, i.e. the order of the CPT keys
+func ReadXMLBIF(r io.Reader) (*BayesianNetwork, error) {
+	var doc xmlBIF
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("XMLBIF: %v", err)
+	}
+
+	doc.Network.trimNames()
+
+	variables := make(map[string]xmlBIFVariable, len(doc.Network.Variables))
+	for _, v := range doc.Network.Variables {
+		if v.Type != "" && v.Type != "nature" {
+			return nil, fmt.Errorf("XMLBIF: variable '%s' has unsupported type '%s'", v.Name, v.Type)
+		}
+		if len(v.Outcomes) != 2 {
+			return nil, fmt.Errorf("XMLBIF: variable '%s' has %d outcomes, only binary variables are supported",
+				v.Name, len(v.Outcomes))
+		}
+		if _, ok := variables[v.Name]; ok {
+			return nil, fmt.Errorf("XMLBIF: duplicate variable '%s'", v.Name)
+		}
+		variables[v.Name] = v
+	}
+
+	nodes := make(BayNodes, 0, len(variables))
+	defined := make(map[string]bool, len(variables))
+	for _, def := range doc.Network.Definitions {
+		v, ok := variables[def.For]
+		if !ok {
+			return nil, fmt.Errorf("XMLBIF: definition for undeclared variable '%s'", def.For)
+		}
+		if defined[def.For] {
+			return nil, fmt.Errorf("XMLBIF: duplicate definition for '%s'", def.For)
+		}
+		defined[def.For] = true
+
+		node, err := decodeXMLBIFDefinition(def)
+		if err != nil {
+			return nil, err
+		}
+		node.SetStates(v.Outcomes[0], v.Outcomes[1])
+		for _, prop := range v.Properties {
+			key, value := splitProperty(prop)
+			node.SetProperty(key, value)
+		}
+		nodes = append(nodes, node)
+	}
+
+	for _, v := range doc.Network.Variables {
+		if !defined[v.Name] {
+			return nil, fmt.Errorf("XMLBIF: variable '%s' has no definition", v.Name)
+		}
+	}
+
+	bn, err := buildBayesianNetwork(nodes...)
+	if err != nil {
+		return nil, fmt.Errorf("XMLBIF: %v", err)
+	}
+
+	bn.SetName(strings.TrimSpace(doc.Network.Name))
+	for _, prop := range doc.Network.Properties {
+		key, value := splitProperty(prop)
+		bn.SetProperty(key, value)
+	}
+
+	return bn, nil
+}
+
+// parse a DEFINITION block into a node
+// - the TABLE lists, for every parent configuration with
+//   the last GIVEN varying fastest, the probability of
+//   each outcome of the FOR variable
+func decodeXMLBIFDefinition(def xmlBIFDefinition) (*Node, error) {
+	fields := strings.Fields(def.Table)
+	rows := 1 << uint(len(def.Given))
+	if len(fields) != 2*rows {
+		return nil, fmt.Errorf("XMLBIF: table of '%s' has %d entries, expected %d",
+			def.For, len(fields), 2*rows)
+	}
+
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("XMLBIF: table of '%s': %v", def.For, err)
+		}
+		values[i] = v
+	}
+
+	probs := make([]float64, rows)
+	for i := range probs {
+		t, f := values[2*i], values[2*i+1]
+		if t < 0 || f < 0 || math.Abs(t+f-1) > xmlBIFTolerance {
+			return nil, fmt.Errorf("XMLBIF: table row %d of '%s' is not a distribution: (%v, %v)",
+				i, def.For, t, f)
+		}
+		probs[i] = t / (t + f)
+	}
+
+	if len(def.Given) == 0 {
+		return NewRootNode(def.For, probs[0]), nil
+	}
+
+	cpt := make(map[string]float64, rows)
+	for i, key := range cptKeys(len(def.Given)) {
+		cpt[key] = probs[i]
+	}
+	return NewNode(def.For, def.Given, cpt), nil
+}
+
+// Encodes the network as an XMLBIF 0.3 document
+// - variables are written in the index order of the network
+func (bn *BayesianNetwork) WriteXMLBIF(w io.Writer) error {
+	if err := bn.checkDiscrete(); err != nil {
+		return err
+	}
+	doc := xmlBIF{
+		Version: "0.3",
+		Network: xmlBIFNetwork{
+			Name:       bn.name,
+			Properties: joinProperties(bn.properties),
+		},
+	}
+
+	for _, node := range bn.nodeIndex {
+		doc.Network.Variables = append(doc.Network.Variables, xmlBIFVariable{
+			Type:       "nature",
+			Name:       node.Name(),
+			Outcomes:   node.States(),
+			Properties: joinProperties(node.Properties()),
+		})
+
+		keys := node.rowKeys()
+		table := make([]string, 0, 2*len(keys))
+		for _, key := range keys {
+			p := node.prob(key)
+			table = append(table, formatProb(p), formatProb(1-p))
+		}
+
+		doc.Network.Definitions = append(doc.Network.Definitions, xmlBIFDefinition{
+			For:   node.Name(),
+			Given: node.GetParentNames(),
+			Table: strings.Join(table, " "),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// formats a probability without the rounding noise
+// of 1-p, i.e. 0.30000000000000004 => 0.3
+func formatProb(p float64) string {
+	return strconv.FormatFloat(p, 'g', 15, 64)
+}
+
+// splits an XMLBIF property "key = value" into its parts
+// - a property without "=" is stored with an empty value
+func splitProperty(prop string) (string, string) {
+	i := strings.Index(prop, "=")
+	if i < 0 {
+		return strings.TrimSpace(prop), ""
+	}
+	return strings.TrimSpace(prop[:i]), strings.TrimSpace(prop[i+1:])
+}
+
+// formats a property map as sorted "key = value" strings
+func joinProperties(props map[string]string) []string {
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	joined := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if props[key] == "" {
+			joined = append(joined, key)
+			continue
+		}
+		joined = append(joined, key+" = "+props[key])
+	}
+	return joined
+}
@@ -0,0 +1,130 @@
+package BayesianNetwork
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+const dogProblem = `<?xml version="1.0"?>
+<BIF VERSION="0.3">
+<NETWORK>
+<NAME>Dog-Problem</NAME>
+<VARIABLE TYPE="nature">
+	<NAME>light-on</NAME>
+	<OUTCOME>true</OUTCOME>
+	<OUTCOME>false</OUTCOME>
+	<PROPERTY>position = (218, 195)</PROPERTY>
+</VARIABLE>
+<VARIABLE TYPE="nature">
+	<NAME>family-out</NAME>
+	<OUTCOME>true</OUTCOME>
+	<OUTCOME>false</OUTCOME>
+</VARIABLE>
+<VARIABLE TYPE="nature">
+	<NAME>bowel-problem</NAME>
+	<OUTCOME>true</OUTCOME>
+	<OUTCOME>false</OUTCOME>
+</VARIABLE>
+<VARIABLE TYPE="nature">
+	<NAME>dog-out</NAME>
+	<OUTCOME>true</OUTCOME>
+	<OUTCOME>false</OUTCOME>
+</VARIABLE>
+<DEFINITION>
+	<FOR>light-on</FOR>
+	<GIVEN>family-out</GIVEN>
+	<TABLE>0.6 0.4 0.05 0.95 </TABLE>
+</DEFINITION>
+<DEFINITION>
+	<FOR>family-out</FOR>
+	<TABLE>0.15 0.85 </TABLE>
+</DEFINITION>
+<DEFINITION>
+	<FOR>bowel-problem</FOR>
+	<TABLE>0.01 0.99 </TABLE>
+</DEFINITION>
+<DEFINITION>
+	<FOR>dog-out</FOR>
+	<GIVEN>bowel-problem</GIVEN>
+	<GIVEN>family-out</GIVEN>
+	<TABLE>0.99 0.01 0.97 0.03 0.9 0.1 0.3 0.7 </TABLE>
+</DEFINITION>
+</NETWORK>
+</BIF>
+`
+
+func TestReadXMLBIF(t *testing.T) {
+	bn, err := ReadXMLBIF(strings.NewReader(dogProblem))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bn.Name() != "Dog-Problem" || bn.NodeCount() != 4 {
+		t.Fatalf("unexpected network %q with %d nodes", bn.Name(), bn.NodeCount())
+	}
+
+	light := bn.GetNode("light-on")
+	if light.Property("position") != "(218, 195)" {
+		t.Errorf("position property: %q", light.Property("position"))
+	}
+	if s := light.States(); s[0] != "true" || s[1] != "false" {
+		t.Errorf("states: %v", s)
+	}
+
+	// the last GIVEN varies fastest
+	dog := bn.GetNode("dog-out")
+	exp := map[string]float64{"TT": 0.99, "TF": 0.97, "FT": 0.9, "FF": 0.3}
+	for key, p := range exp {
+		if math.Abs(dog.cpt[key]-p) > 1e-12 {
+			t.Errorf("dog-out[%s] = %v, expected %v", key, dog.cpt[key], p)
+		}
+	}
+}
+
+func TestXMLBIFRoundTrip(t *testing.T) {
+	bn := BuildStudentNetwork()
+	bn.SetName("Student")
+	bn.GetNode("P").SetProperty("position", "(1, 2)")
+
+	var buf bytes.Buffer
+	if err := bn.WriteXMLBIF(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := ReadXMLBIF(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Name() != "Student" {
+		t.Errorf("name: %q", decoded.Name())
+	}
+	if decoded.GetNode("P").Property("position") != "(1, 2)" {
+		t.Errorf("lost property on P")
+	}
+
+	for _, node := range bn.GetNodes() {
+		other := decoded.GetNode(node.Name())
+		if other == nil {
+			t.Fatalf("%s missing after round trip", node.Name())
+		}
+		if !other.validateParents(node.GetParentNames()) {
+			t.Errorf("%s: parents %v != %v", node.Name(), other.GetParentNames(), node.GetParentNames())
+		}
+		for _, key := range node.rowKeys() {
+			if math.Abs(node.cpt[key]-other.cpt[key]) > 1e-12 {
+				t.Errorf("%s[%s]: %v != %v", node.Name(), key, node.cpt[key], other.cpt[key])
+			}
+		}
+	}
+}
+
+func TestReadXMLBIFRejectsNonBinary(t *testing.T) {
+	doc := strings.Replace(dogProblem, "<OUTCOME>false</OUTCOME>\n</VARIABLE>",
+		"<OUTCOME>false</OUTCOME>\n\t<OUTCOME>maybe</OUTCOME>\n</VARIABLE>", 1)
+	if _, err := ReadXMLBIF(strings.NewReader(doc)); err == nil {
+		t.Error("expected an error for a variable with three outcomes")
+	}
+}